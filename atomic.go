@@ -0,0 +1,146 @@
+package iplist
+
+import (
+	"errors"
+	"net/netip"
+	"sync/atomic"
+)
+
+// ErrMappedDB is returned by the lookup helpers of AtomicDB when the current
+// DB was opened without WithHeapStrings from a memory-mapped file: the
+// results would point into a mapping that a concurrent Swap may unmap.
+var ErrMappedDB = errors.New("iplist: db is mapped; open it with WithHeapStrings")
+
+// AtomicDB holds a *DB that can be swapped while lookups are in flight.
+//
+// A replaced DB is closed only after every Handle acquired on it has been
+// released, so readers never see an unmapped file. AtomicDB is safe for
+// concurrent use.
+//
+// Strings in Result and ResultIDs.ProviderIDs point into the DB's mapping
+// unless the DB was opened with WithHeapStrings. The Lookup helpers on
+// AtomicDB release their Handle before returning, so they fail with
+// ErrMappedDB for such DBs; use Acquire and the Handle's DB instead, or open
+// the DB with WithHeapStrings.
+type AtomicDB struct {
+	cur atomic.Pointer[Handle]
+}
+
+// Handle is a reference-counted reference to one DB generation.
+// Call Release exactly once when done with DB.
+type Handle struct {
+	db *DB
+	// refs counts AtomicDB's own reference plus acquired handles.
+	refs atomic.Int64
+}
+
+// NewAtomicDB returns an AtomicDB serving db. db may be nil.
+func NewAtomicDB(db *DB) *AtomicDB {
+	a := &AtomicDB{}
+	a.Swap(db)
+	return a
+}
+
+// Acquire returns a Handle on the current DB, or nil if there is none.
+func (a *AtomicDB) Acquire() *Handle {
+	for {
+		h := a.cur.Load()
+		if h == nil {
+			return nil
+		}
+		if h.retain() {
+			return h
+		}
+		// h was retired between Load and retain; the next Load sees its successor.
+	}
+}
+
+// Swap installs db (which may be nil) and retires the previous DB.
+// The previous DB is closed once its last Handle is released.
+func (a *AtomicDB) Swap(db *DB) {
+	var h *Handle
+	if db != nil {
+		h = &Handle{db: db}
+		h.refs.Store(1)
+	}
+	if old := a.cur.Swap(h); old != nil {
+		old.Release()
+	}
+}
+
+// Close retires the current DB. Lookups fail with ErrInvalidDB afterwards.
+func (a *AtomicDB) Close() error {
+	a.Swap(nil)
+	return nil
+}
+
+// DB returns the DB this handle refers to.
+func (h *Handle) DB() *DB {
+	return h.db
+}
+
+// Release drops the reference taken by Acquire.
+func (h *Handle) Release() {
+	if h.refs.Add(-1) == 0 {
+		_ = h.db.Close()
+	}
+}
+
+func (h *Handle) retain() bool {
+	for {
+		n := h.refs.Load()
+		if n <= 0 {
+			return false
+		}
+		if h.refs.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// Lookup is like (*DB).Lookup on the current DB.
+func (a *AtomicDB) Lookup(ip string) (Result, bool, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return Result{}, false, ErrInvalidIP
+	}
+	return a.LookupAddr(addr)
+}
+
+// LookupAddr is like (*DB).LookupAddr on the current DB.
+func (a *AtomicDB) LookupAddr(addr netip.Addr) (Result, bool, error) {
+	var out Result
+	matched, err := a.LookupAddrInto(addr, &out)
+	if err != nil {
+		return Result{}, false, err
+	}
+	return out, matched, nil
+}
+
+// LookupAddrInto is like (*DB).LookupAddrInto on the current DB.
+func (a *AtomicDB) LookupAddrInto(addr netip.Addr, dst *Result) (bool, error) {
+	h := a.Acquire()
+	if h == nil {
+		return false, ErrInvalidDB
+	}
+	defer h.Release()
+	if !h.db.heapStrings {
+		return false, ErrMappedDB
+	}
+	return h.db.LookupAddrInto(addr, dst)
+}
+
+// LookupAddrIDsInto is like (*DB).LookupAddrIDsInto on the current DB.
+// IDs are only meaningful for the DB generation that produced them; use
+// Acquire to decode them against the same DB.
+func (a *AtomicDB) LookupAddrIDsInto(addr netip.Addr, dst *ResultIDs) (bool, error) {
+	h := a.Acquire()
+	if h == nil {
+		return false, ErrInvalidDB
+	}
+	defer h.Release()
+	if !h.db.heapStrings {
+		return false, ErrMappedDB
+	}
+	return h.db.LookupAddrIDsInto(addr, dst)
+}
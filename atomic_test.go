@@ -0,0 +1,136 @@
+package iplist
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+)
+
+func TestAtomicDBProviderIDsAfterSwap(t *testing.T) {
+	b := NewBuilder()
+	_ = b.AddProvider(netip.MustParsePrefix("10.0.0.0/8"), "carrier", "", ProviderKindISP)
+	_ = b.AddProvider(netip.MustParsePrefix("10.1.0.0/16"), "cloud", "", ProviderKindCloud)
+	db, err := Open(writeTestDB(t, b), WithHeapStrings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAtomicDB(db)
+
+	var ids ResultIDs
+	if ok, err := a.LookupAddrIDsInto(netip.MustParseAddr("10.1.0.1"), &ids); !ok || err != nil {
+		t.Fatalf("lookup: %v %v", ok, err)
+	}
+	want := append([]uint32(nil), ids.ProviderIDs...)
+	if len(want) != 2 {
+		t.Fatalf("ProviderIDs = %v, want two providers", want)
+	}
+	a.Swap(nil) // unmaps the file
+	for i, id := range ids.ProviderIDs {
+		if id != want[i] {
+			t.Fatalf("ProviderIDs = %v after Swap, want %v", ids.ProviderIDs, want)
+		}
+	}
+}
+
+// countryTestDB writes a database mapping 1.0.0.0/24 to code and returns its path.
+func countryTestDB(t *testing.T, code string) string {
+	t.Helper()
+	b := NewBuilder()
+	if err := b.AddCountry(netip.MustParsePrefix("1.0.0.0/24"), code); err != nil {
+		t.Fatal(err)
+	}
+	return writeTestDB(t, b)
+}
+
+func openTestDB(t *testing.T, path string, opts ...OpenOption) *DB {
+	t.Helper()
+	db, err := Open(path, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestAtomicDBSwapRelease(t *testing.T) {
+	db1 := openTestDB(t, countryTestDB(t, "CN"))
+	db2 := openTestDB(t, countryTestDB(t, "US"), WithHeapStrings())
+	a := NewAtomicDB(db1)
+	// db1 is mapped, so its results must not leave a Handle.
+	if _, _, err := a.Lookup("1.0.0.1"); err != ErrMappedDB {
+		t.Errorf("mapped DB: %v, want ErrMappedDB", err)
+	}
+	var ids ResultIDs
+	if _, err := a.LookupAddrIDsInto(netip.MustParseAddr("1.0.0.1"), &ids); err != ErrMappedDB {
+		t.Errorf("mapped DB IDs: %v, want ErrMappedDB", err)
+	}
+
+	h := a.Acquire()
+	if h.DB() != db1 {
+		t.Fatal("Acquire did not return the current DB")
+	}
+	a.Swap(db2)
+	if db1.raw == nil {
+		t.Fatal("Swap closed a DB with an outstanding Handle")
+	}
+	if res, _, _ := h.DB().Lookup("1.0.0.1"); res.CountryCode != "CN" {
+		t.Errorf("old handle: country %q, want CN", res.CountryCode)
+	}
+	if res, _, err := a.Lookup("1.0.0.1"); err != nil || res.CountryCode != "US" {
+		t.Errorf("after Swap: country %q, %v; want US", res.CountryCode, err)
+	}
+	h.Release()
+	if db1.raw != nil {
+		t.Error("last Release did not close the retired DB")
+	}
+
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if db2.raw != nil {
+		t.Error("Close did not close the current DB")
+	}
+	if a.Acquire() != nil {
+		t.Error("Acquire after Close returned a Handle")
+	}
+	if _, _, err := a.Lookup("1.0.0.1"); err != ErrInvalidDB {
+		t.Errorf("Lookup after Close: %v, want ErrInvalidDB", err)
+	}
+}
+
+func TestAtomicDBLookupDuringSwap(t *testing.T) {
+	paths := []string{countryTestDB(t, "CN"), countryTestDB(t, "US")}
+	// Results are read after the lookup has released its Handle.
+	a := NewAtomicDB(openTestDB(t, paths[0], WithHeapStrings()))
+	defer a.Close()
+
+	stop := make(chan struct{})
+	errs := make(chan error, 4)
+	for range 4 {
+		go func() {
+			var res Result
+			addr := netip.MustParseAddr("1.0.0.1")
+			for {
+				select {
+				case <-stop:
+					errs <- nil
+					return
+				default:
+				}
+				ok, err := a.LookupAddrInto(addr, &res)
+				if err != nil || !ok || (res.CountryCode != "CN" && res.CountryCode != "US") {
+					errs <- fmt.Errorf("lookup: %v %v %q", ok, err, res.CountryCode)
+					return
+				}
+			}
+		}()
+	}
+	for i := range 50 {
+		a.Swap(openTestDB(t, paths[i%2], WithHeapStrings()))
+	}
+	close(stop)
+	for range 4 {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -104,6 +104,22 @@ func BenchmarkLookupAddrIDsInto_8_160_0_3(b *testing.B) {
 	}
 }
 
+func BenchmarkLookupAddrIDsInto_2400_3200__1(b *testing.B) {
+	db := openBenchDB(b)
+	addr := netip.MustParseAddr("2400:3200::1")
+	var dst ResultIDs
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := db.LookupAddrIDsInto(addr, &dst)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func BenchmarkLookupIPv4Uint32IDsInto_8_160_0_3(b *testing.B) {
 	db := openBenchDB(b)
 	addr := netip.MustParseAddr("8.160.0.3")
@@ -168,3 +184,24 @@ func BenchmarkTableLookupOnly_8_160_0_3(b *testing.B) {
 		_, _ = t.lookup(ip)
 	}
 }
+
+func BenchmarkOpen(b *testing.B) {
+	path := os.Getenv("IPLIST_DB")
+	if path == "" {
+		path = filepath.Join(b.TempDir(), "iplist.db")
+		if err := Build("data", path); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		db, err := Open(path, WithoutChecksum())
+		if err != nil {
+			b.Fatal(err)
+		}
+		_ = db.Close()
+	}
+}
@@ -1,315 +1,203 @@
 package iplist
 
 import (
-	"bufio"
 	"bytes"
+	"crypto/ed25519"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
-	"net"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
-	"strings"
+	"strconv"
 	"time"
 )
 
-// Build creates a database file from the repository-style data directory.
-//
-// Expected inputs:
-// - dataDir/country/*.txt (ISO 3166-1 alpha-2)
-// - dataDir/cncity/*.txt (CN admin code, 6 digits)
-// - dataDir/isp/*.txt (provider key)
-func Build(dataDir, outPath string) error {
-	providerNames := defaultProviderNames()
-	cloudSet := defaultCloudSet()
-
-	strIndex := newStringInterner()
-
-	countryLabelIndex := make(map[string]uint32)
-	cnLabelIndex := make(map[string]uint32)
-	providerLabelIndex := make(map[string]uint32)
+// BuildOption configures Build.
+type BuildOption func(*buildConfig)
+
+type buildConfig struct {
+	sources      []string
+	dataRevision string
+	extra        map[string]string
+	signingKey   ed25519.PrivateKey
+	buildTime    time.Time
+	builderVer   string
+
+	conflictPolicy   ConflictPolicy
+	conflictPriority []string
+	conflictReport   func(Conflict)
+
+	// Inputs kept beside the data directory, see inputFile.
+	providerRegistry    string
+	providerRegistrySet bool
+	cnMigrations        string
+	cnMigrationsSet     bool
+}
 
-	countryLabels := make([]label2, 0, 260)
-	cnLabels := make([]label2, 0, 500)
-	providerLabels := make([]providerLabel, 0, 64)
+// WithSources records the names of the upstream data sets
+// (e.g. OpenIPDB, IPinfo, bgp.tools) in the metadata section.
+func WithSources(names ...string) BuildOption {
+	return func(c *buildConfig) { c.sources = append(c.sources, names...) }
+}
 
-	getCountryLabel := func(code string) uint32 {
-		if idx, ok := countryLabelIndex[code]; ok {
-			return idx
-		}
-		name, _ := docsCountryName(code)
-		if name == "" {
-			name = code
-		}
-		idx := uint32(len(countryLabels))
-		countryLabelIndex[code] = idx
-		countryLabels = append(countryLabels, label2{Code: strIndex.intern(code), Name: strIndex.intern(name)})
-		return idx
-	}
-	getCNLabel := func(code string) uint32 {
-		if idx, ok := cnLabelIndex[code]; ok {
-			return idx
-		}
-		name, _ := docsCNCityName(code)
-		if name == "" {
-			name = code
-		}
-		idx := uint32(len(cnLabels))
-		cnLabelIndex[code] = idx
-		cnLabels = append(cnLabels, label2{Code: strIndex.intern(code), Name: strIndex.intern(name)})
-		return idx
-	}
-	getProviderLabel := func(key string) uint32 {
-		if idx, ok := providerLabelIndex[key]; ok {
-			return idx
-		}
-		name := providerNames[key]
-		if name == "" {
-			name = key
-		}
-		kind := ProviderKindISP
-		if cloudSet[key] {
-			kind = ProviderKindCloud
-		}
-		idx := uint32(len(providerLabels))
-		providerLabelIndex[key] = idx
-		providerLabels = append(providerLabels, providerLabel{Key: strIndex.intern(key), Name: strIndex.intern(name), Kind: uint32(kind)})
-		return idx
-	}
+// WithDataRevision records the revision of the data directory
+// (usually a git commit) in the metadata section.
+func WithDataRevision(rev string) BuildOption {
+	return func(c *buildConfig) { c.dataRevision = rev }
+}
 
-	var countryEntries []entry
-	{
-		glob := filepath.Join(dataDir, "country", "*.txt")
-		files, _ := filepath.Glob(glob)
-		for _, p := range files {
-			base := strings.TrimSuffix(filepath.Base(p), ".txt")
-			if len(base) != 2 {
-				continue
-			}
-			label := getCountryLabel(base)
-			rs, err := readCIDRFileAsRanges(p)
-			if err != nil {
-				return err
-			}
-			for _, r := range rs {
-				countryEntries = append(countryEntries, entry{Start: r.start, End: r.end, Label: label})
-			}
+// WithMetadata adds a free-form key/value to the metadata section.
+func WithMetadata(key, value string) BuildOption {
+	return func(c *buildConfig) {
+		if c.extra == nil {
+			c.extra = make(map[string]string)
 		}
+		c.extra[key] = value
 	}
+}
 
-	var cnProvEntries []entry
-	var cnCityEntries []entry
-	{
-		glob := filepath.Join(dataDir, "cncity", "*.txt")
-		files, _ := filepath.Glob(glob)
-		for _, p := range files {
-			code := strings.TrimSuffix(filepath.Base(p), ".txt")
-			if len(code) != 6 {
-				continue
-			}
-			label := getCNLabel(code)
-			rs, err := readCIDRFileAsRanges(p)
-			if err != nil {
-				return err
-			}
-			isProv := strings.HasSuffix(code, "0000")
-			isCity := strings.HasSuffix(code, "00") && !isProv
-			if !isProv && !isCity {
-				continue
-			}
-			for _, r := range rs {
-				e := entry{Start: r.start, End: r.end, Label: label}
-				if isCity {
-					cnCityEntries = append(cnCityEntries, e)
-				} else {
-					cnProvEntries = append(cnProvEntries, e)
-				}
-			}
-		}
-	}
+// WithSigningKey signs the database with an ed25519 private key.
+// Readers can require the signature with WithPublicKey.
+func WithSigningKey(key ed25519.PrivateKey) BuildOption {
+	return func(c *buildConfig) { c.signingKey = key }
+}
 
-	var providerEntries []entry
-	{
-		glob := filepath.Join(dataDir, "isp", "*.txt")
-		files, _ := filepath.Glob(glob)
-		for _, p := range files {
-			key := strings.TrimSuffix(filepath.Base(p), ".txt")
-			label := getProviderLabel(key)
-			rs, err := readCIDRFileAsRanges(p)
-			if err != nil {
-				return err
-			}
-			for _, r := range rs {
-				providerEntries = append(providerEntries, entry{Start: r.start, End: r.end, Label: label})
-			}
-		}
-	}
+// WithBuildTime sets the build time stamped into the header. Without it the
+// SOURCE_DATE_EPOCH environment variable is used if set, else the current time.
+// Together with identical inputs this makes the output byte-for-byte
+// reproducible.
+func WithBuildTime(t time.Time) BuildOption {
+	return func(c *buildConfig) { c.buildTime = t }
+}
 
-	// Sort and validate for lookup.
-	sort.Slice(countryEntries, func(i, j int) bool { return countryEntries[i].Start < countryEntries[j].Start })
-	sort.Slice(cnProvEntries, func(i, j int) bool { return cnProvEntries[i].Start < cnProvEntries[j].Start })
-	sort.Slice(cnCityEntries, func(i, j int) bool { return cnCityEntries[i].Start < cnCityEntries[j].Start })
-	sort.Slice(providerEntries, func(i, j int) bool { return providerEntries[i].Start < providerEntries[j].Start })
+// WithBuilderVersion overrides the builder version recorded in the metadata
+// section. It is meant for rebuilding an existing file to compare it.
+func WithBuilderVersion(v string) BuildOption {
+	return func(c *buildConfig) { c.builderVer = v }
+}
 
-	if err := validateNoOverlapDifferentLabel(countryEntries); err != nil {
-		return fmt.Errorf("country: %w", err)
-	}
-	if err := validateNoOverlapDifferentLabel(cnProvEntries); err != nil {
-		return fmt.Errorf("cn province: %w", err)
-	}
-	if err := validateNoOverlapDifferentLabel(cnCityEntries); err != nil {
-		return fmt.Errorf("cn city: %w", err)
+func (c *buildConfig) resolveBuildTime() (time.Time, error) {
+	if !c.buildTime.IsZero() {
+		return c.buildTime, nil
 	}
-	// Provider ranges might overlap in future; we currently still enforce disjointness.
-	if err := validateNoOverlapDifferentLabel(providerEntries); err != nil {
-		return fmt.Errorf("provider: %w", err)
-	}
-
-	// Note: we intentionally do not densify ranges by default.
-	// Densifying (filling gaps) can significantly increase the number of entries,
-	// which hurts cache locality and makes binary search slower on this dataset.
-
-	// Build strings blob.
-	stringsBlob := strIndex.encode()
-
-	buf := &bytes.Buffer{}
-	buf.Grow(16 * 1024)
-
-	// Reserve header.
-	buf.Write(make([]byte, headerSize))
-
-	stringsOff := uint32(buf.Len())
-	buf.Write(stringsBlob)
-	stringsSize := uint32(len(stringsBlob))
-
-	// Align following fixed-width tables for safe unsafe.Slice on strict-alignment arches.
-	// Header is 64 bytes; by aligning here we keep all later offsets aligned.
-	if pad := (-buf.Len()) & 7; pad != 0 {
-		buf.Write(make([]byte, pad))
+	if v := os.Getenv("SOURCE_DATE_EPOCH"); v != "" {
+		sec, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("iplist: invalid SOURCE_DATE_EPOCH %q", v)
+		}
+		return time.Unix(sec, 0), nil
 	}
+	return time.Now(), nil
+}
 
-	// Section header (v2, 160 bytes)
-	secOff := uint32(buf.Len())
-	secSize := uint32(160)
-	buf.Write(make([]byte, secSize))
-
-	countryLabelsOff, countryLabelsCnt, err := writeFixed(buf, countryLabels)
-	if err != nil {
+// Build creates a database file from the repository-style data directory.
+// It is a thin wrapper over Builder.LoadFS and Builder.WriteTo.
+//
+// Each file may mix IPv4 and IPv6 CIDRs.
+//
+// Expected inputs (see LoadFS):
+// - dataDir/country/*.txt (ISO 3166-1 alpha-2)
+// - dataDir/country/XX/XX-YY.txt (ISO 3166-2 subdivisions of country XX)
+// - dataDir/cncity/*.txt (CN admin code, 6 digits; province, city or district)
+// - dataDir/isp/*.txt (provider key)
+// - dataDir/special/*.txt (special set name)
+// - dataDir/providers.tsv (provider registry, optional)
+// - dataDir/<category>/*.txt (custom category; the file name is the label)
+//
+// The maintained provider registry and CN admin code migrations are kept
+// outside dataDir, which is regenerated from scratch: Build reads
+// DefaultProviderRegistry and DefaultCNMigrations beside it if present, or
+// the files given by WithProviderRegistry and WithCNMigrations. Entries of
+// dataDir/providers.tsv take precedence over the registry file.
+//
+// outPath is replaced by rename, which is safe while a Reloader serves it.
+func Build(dataDir, outPath string, opts ...BuildOption) error {
+	b := NewBuilder(opts...)
+	c := &b.cfg
+	registry := inputFile(dataDir, c.providerRegistry, c.providerRegistrySet, DefaultProviderRegistry)
+	if err := loadInputFile(registry, b.LoadProviderRegistry); err != nil {
 		return err
 	}
-	cnLabelsOff, cnLabelsCnt, err := writeFixed(buf, cnLabels)
-	if err != nil {
+	if err := b.LoadFS(os.DirFS(dataDir)); err != nil {
 		return err
 	}
-	providerLabelsOff, providerLabelsCnt, err := writeFixed(buf, providerLabels)
-	if err != nil {
+	migrations := inputFile(dataDir, c.cnMigrations, c.cnMigrationsSet, DefaultCNMigrations)
+	if err := loadInputFile(migrations, b.LoadCNMigrations); err != nil {
 		return err
 	}
-	countryStarts, countryEnds, countryLbls := splitEntries(countryEntries)
-	cnProvStarts, cnProvEnds, cnProvLbls := splitEntries(cnProvEntries)
-	cnCityStarts, cnCityEnds, cnCityLbls := splitEntries(cnCityEntries)
-	providerStarts, providerEnds, providerLbls := splitEntries(providerEntries)
-
-	countryStartsOff, countryEntriesCnt, err := writeU32Slice(buf, countryStarts)
+	out, err := b.encode()
 	if err != nil {
 		return err
 	}
-	countryEndsOff, _, err := writeU32Slice(buf, countryEnds)
-	if err != nil {
-		return err
+	return writeFileAtomic(outPath, out)
+}
+
+// inputFile returns the file Build reads for an input kept beside the data
+// directory: path if it was set by an option, else def relative to the
+// parent of dataDir if that exists. An empty result means none.
+func inputFile(dataDir, path string, set bool, def string) string {
+	if set {
+		return path
 	}
-	countryLblsOff, _, err := writeU32Slice(buf, countryLbls)
-	if err != nil {
-		return err
+	name := filepath.Join(filepath.Dir(filepath.Clean(dataDir)), def)
+	if _, err := os.Stat(name); errors.Is(err, fs.ErrNotExist) {
+		return ""
 	}
+	return name
+}
 
-	cnProvStartsOff, cnProvEntriesCnt, err := writeU32Slice(buf, cnProvStarts)
-	if err != nil {
-		return err
+// loadInputFile passes the contents of name to load, unless name is empty.
+func loadInputFile(name string, load func(io.Reader) error) error {
+	if name == "" {
+		return nil
 	}
-	cnProvEndsOff, _, err := writeU32Slice(buf, cnProvEnds)
+	f, err := os.Open(name)
 	if err != nil {
 		return err
 	}
-	cnProvLblsOff, _, err := writeU32Slice(buf, cnProvLbls)
-	if err != nil {
-		return err
+	defer f.Close()
+	if err := load(f); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
 	}
+	return nil
+}
 
-	cnCityStartsOff, cnCityEntriesCnt, err := writeU32Slice(buf, cnCityStarts)
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// over path, so a Reloader serving path never maps a partly written file.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
 	if err != nil {
 		return err
 	}
-	cnCityEndsOff, _, err := writeU32Slice(buf, cnCityEnds)
-	if err != nil {
-		return err
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
 	}
-	cnCityLblsOff, _, err := writeU32Slice(buf, cnCityLbls)
-	if err != nil {
-		return err
+	if err == nil {
+		err = os.Chmod(tmp, 0o644)
 	}
-
-	providerStartsOff, providerEntriesCnt, err := writeU32Slice(buf, providerStarts)
-	if err != nil {
-		return err
+	if err == nil {
+		err = os.Rename(tmp, path)
 	}
-	providerEndsOff, _, err := writeU32Slice(buf, providerEnds)
 	if err != nil {
-		return err
-	}
-	providerLblsOff, _, err := writeU32Slice(buf, providerLbls)
-	if err != nil {
-		return err
+		_ = os.Remove(tmp)
 	}
+	return err
+}
 
-	// Fill header.
-	out := buf.Bytes()
-	copy(out[0:4], []byte(magicV4))
-	binary.LittleEndian.PutUint16(out[4:6], version2)
-	binary.LittleEndian.PutUint16(out[6:8], 0)
-	binary.LittleEndian.PutUint64(out[8:16], uint64(time.Now().Unix()))
-	binary.LittleEndian.PutUint32(out[16:20], stringsOff)
-	binary.LittleEndian.PutUint32(out[20:24], stringsSize)
-	binary.LittleEndian.PutUint32(out[24:28], secOff)
-	binary.LittleEndian.PutUint32(out[28:32], secSize)
-	// remaining header bytes reserved
-
-	// Fill section header.
-	sec := out[secOff : secOff+secSize]
-	putU32 := func(off int, v uint32) { binary.LittleEndian.PutUint32(sec[off:off+4], v) }
-	putU32(0, countryLabelsOff)
-	putU32(4, countryLabelsCnt)
-	putU32(8, cnLabelsOff)
-	putU32(12, cnLabelsCnt)
-	putU32(16, providerLabelsOff)
-	putU32(20, providerLabelsCnt)
-
-	// Each table: startsOff, endsOff, labelsOff, count
-	putU32(24, countryStartsOff)
-	putU32(28, countryEndsOff)
-	putU32(32, countryLblsOff)
-	putU32(36, countryEntriesCnt)
-
-	putU32(40, cnProvStartsOff)
-	putU32(44, cnProvEndsOff)
-	putU32(48, cnProvLblsOff)
-	putU32(52, cnProvEntriesCnt)
-
-	putU32(56, cnCityStartsOff)
-	putU32(60, cnCityEndsOff)
-	putU32(64, cnCityLblsOff)
-	putU32(68, cnCityEntriesCnt)
-
-	putU32(72, providerStartsOff)
-	putU32(76, providerEndsOff)
-	putU32(80, providerLblsOff)
-	putU32(84, providerEntriesCnt)
-
-	if err := os.WriteFile(outPath, out, 0o644); err != nil {
-		return err
+func countCategory(v4 []entry, v6 []entry6) CategoryCount {
+	labels := make(map[uint32]struct{})
+	for _, e := range v4 {
+		labels[e.Label] = struct{}{}
 	}
-	return nil
+	for _, e := range v6 {
+		labels[e.Label] = struct{}{}
+	}
+	return CategoryCount{Ranges4: len(v4), Ranges6: len(v6), Labels: len(labels)}
 }
 
 func splitEntries(entries []entry) (starts, ends, labels []uint32) {
@@ -324,90 +212,20 @@ func splitEntries(entries []entry) (starts, ends, labels []uint32) {
 	return
 }
 
-func writeU32Slice(buf *bytes.Buffer, s []uint32) (off uint32, count uint32, err error) {
-	off = uint32(buf.Len())
-	count = uint32(len(s))
-	if len(s) == 0 {
-		return off, 0, nil
-	}
-	return off, count, binary.Write(buf, binary.LittleEndian, s)
-}
-
-func writeFixed[T any](buf *bytes.Buffer, s []T) (off uint32, count uint32, err error) {
-	off = uint32(buf.Len())
-	count = uint32(len(s))
-	if len(s) == 0 {
-		return off, 0, nil
-	}
-	return off, count, binary.Write(buf, binary.LittleEndian, s)
-}
-
-type ipRange struct{ start, end uint32 }
-
-func readCIDRFileAsRanges(path string) ([]ipRange, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	var out []ipRange
-	r := bufio.NewScanner(f)
-	for r.Scan() {
-		line := strings.TrimSpace(r.Text())
-		if line == "" {
-			continue
-		}
-		_, n, err := net.ParseCIDR(line)
-		if err != nil {
-			return nil, fmt.Errorf("%s: parse CIDR %q: %w", path, line, err)
-		}
-		v4 := n.IP.To4()
-		if v4 == nil {
-			continue
-		}
-		maskSize, _ := n.Mask.Size()
-		start := uint32(v4[0])<<24 | uint32(v4[1])<<16 | uint32(v4[2])<<8 | uint32(v4[3])
-		size := uint32(1) << (32 - uint32(maskSize))
-		end := start + size - 1
-		out = append(out, ipRange{start: start, end: end})
-	}
-	if err := r.Err(); err != nil {
-		return nil, err
-	}
-
-	// Normalize by merging overlaps/adjacent ranges.
-	sort.Slice(out, func(i, j int) bool { return out[i].start < out[j].start })
-	merged := out[:0]
-	for _, cur := range out {
-		if len(merged) == 0 {
-			merged = append(merged, cur)
-			continue
-		}
-		last := &merged[len(merged)-1]
-		if cur.start <= last.end+1 {
-			if cur.end > last.end {
-				last.end = cur.end
-			}
-			continue
-		}
-		merged = append(merged, cur)
+func splitEntries6(entries []entry6) (starts, ends []u128, labels []uint32) {
+	starts = make([]u128, len(entries))
+	ends = make([]u128, len(entries))
+	labels = make([]uint32, len(entries))
+	for i, e := range entries {
+		starts[i] = e.Start
+		ends[i] = e.End
+		labels[i] = e.Label
 	}
-	return merged, nil
+	return
 }
 
-func validateNoOverlapDifferentLabel(entries []entry) error {
-	for i := 1; i < len(entries); i++ {
-		prev := entries[i-1]
-		cur := entries[i]
-		if cur.Start <= prev.End {
-			if cur.Label != prev.Label {
-				return fmt.Errorf("overlap %d-%d (label %d) with %d-%d (label %d)", prev.Start, prev.End, prev.Label, cur.Start, cur.End, cur.Label)
-			}
-			// Same label overlap should have been merged by input merge; tolerate.
-		}
-	}
-	return nil
+func sortEntries6(entries []entry6) {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Start.less(entries[j].Start) })
 }
 
 // --- string interner ---
@@ -0,0 +1,101 @@
+package iplist
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeDataDir writes files, keyed by slash-separated path, into a temporary
+// data directory and returns it.
+func writeDataDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// buildDataDir runs Build on a data directory holding files and returns the
+// path of the database.
+func buildDataDir(t *testing.T, files map[string]string, opts ...BuildOption) string {
+	t.Helper()
+	out := filepath.Join(t.TempDir(), "iplist.db")
+	if err := Build(writeDataDir(t, files), out, opts...); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestBuildIPv6(t *testing.T) {
+	db, err := Open(buildDataDir(t, map[string]string{
+		"country/CN.txt":       "1.0.1.0/24\n240e::/20\n",
+		"country/US.txt":       "8.8.8.0/24\n2001:4860::/32\n",
+		"country/JP.txt":       "::ffff:203.0.113.0/120\n",
+		"cncity/440000.txt":    "240e:100::/24\n",
+		"cncity/440300.txt":    "240e:100::/32\n",
+		"isp/chinatelecom.txt": "1.0.1.0/24\n240e::/20\n",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		ip                          string
+		country, prov, city, vendor string
+	}{
+		{"1.0.1.1", "CN", "", "", "chinatelecom"},
+		{"::ffff:1.0.1.1", "CN", "", "", "chinatelecom"},
+		{"240e:100::1", "CN", "440000", "440300", "chinatelecom"},
+		{"240e:1ff::1", "CN", "440000", "", "chinatelecom"},
+		{"240e:fff::1", "CN", "", "", "chinatelecom"},
+		{"2001:4860::8888", "US", "", "", ""},
+		{"203.0.113.7", "JP", "", "", ""},
+		{"::ffff:203.0.113.7", "JP", "", "", ""},
+	}
+	for _, tt := range tests {
+		res, ok, err := db.Lookup(tt.ip)
+		if err != nil || !ok {
+			t.Fatalf("%s: %v %v", tt.ip, ok, err)
+		}
+		if res.CountryCode != tt.country || res.CNProvinceCode != tt.prov || res.CNCityCode != tt.city || res.ProviderKey != tt.vendor {
+			t.Errorf("%s: got %s/%s/%s/%s", tt.ip, res.CountryCode, res.CNProvinceCode, res.CNCityCode, res.ProviderKey)
+		}
+	}
+	if _, ok, _ := db.Lookup("2001:db8::1"); ok {
+		t.Error("2001:db8::1 matched")
+	}
+
+	// The ID and Into variants agree with Lookup on IPv6 addresses.
+	addr := netip.MustParseAddr("240e:100::1")
+	var ids ResultIDs
+	if ok, err := db.LookupAddrIDsInto(addr, &ids); err != nil || !ok {
+		t.Fatalf("LookupAddrIDsInto(%s): %v %v", addr, ok, err)
+	}
+	var res Result
+	if ok, err := db.LookupAddrInto(addr, &res); err != nil || !ok {
+		t.Fatalf("LookupAddrInto(%s): %v %v", addr, ok, err)
+	}
+	if code, _, _ := db.CountryByID(ids.CountryID); code != "CN" || res.CNCityCode != "440300" {
+		t.Errorf("%s: country id %d (%s), city %s", addr, ids.CountryID, code, res.CNCityCode)
+	}
+
+	got, _, err := db.ProviderIPs("chinatelecom")
+	if want := []string{"1.0.1.0/24", "240e::/20"}; err != nil || !slices.Equal(got, want) {
+		t.Errorf("ProviderIPs(chinatelecom) = %v, %v; want %v", got, err, want)
+	}
+	// Mapped prefixes are stored in the IPv4 tables.
+	if got, err := db.CountryIPs("JP"); err != nil || !slices.Equal(got, []string{"203.0.113.0/24"}) {
+		t.Errorf("CountryIPs(JP) = %v, %v", got, err)
+	}
+}
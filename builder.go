@@ -0,0 +1,775 @@
+package iplist
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/netip"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Builder assembles a database from ranges added in code, for callers that
+// produce ranges from their own sources rather than a data directory.
+//
+// Ranges with the same label may overlap or repeat; they are merged when the
+// database is written. Overlaps between different labels of a category are
+// handled by the ConflictPolicy (by default they fail the build); provider
+// ranges may overlap freely. A Builder is not safe for concurrent use.
+type Builder struct {
+	cfg buildConfig
+
+	// Built-in provider names and kinds, used when neither AddProvider nor
+	// the registry sets them.
+	providerNames map[string]string
+	providerKinds map[string]ProviderKind
+	registry      map[string]ProviderInfo
+
+	countries labelSet
+	subdivs   labelSet
+	cnRegions labelSet
+	providers labelSet
+	specials  labelSet
+	// providerInfo is indexed like providers.keys.
+	providerInfo []providerInfo
+
+	country  rangeTable
+	subdiv   rangeTable
+	cn       rangeTable // all CN levels; split by code when written
+	provider rangeTable
+	special  rangeTable
+
+	subdivNames  map[string]string
+	cnMigrations []cnMigration
+	categories   map[string]*customBuild
+
+	// sources maps category and key to the file LoadFS read them from.
+	sources map[string]string
+}
+
+type providerInfo struct {
+	name string
+	kind ProviderKind
+}
+
+// NewBuilder returns an empty Builder.
+func NewBuilder(opts ...BuildOption) *Builder {
+	b := &Builder{
+		providerNames: defaultProviderNames(),
+		providerKinds: defaultProviderKinds(),
+	}
+	for _, opt := range opts {
+		opt(&b.cfg)
+	}
+	return b
+}
+
+// AddCountry maps prefix to an ISO 3166-1 alpha-2 country code.
+func (b *Builder) AddCountry(prefix netip.Prefix, code string) error {
+	if len(code) != 2 {
+		return fmt.Errorf("iplist: invalid country code %q", code)
+	}
+	if !prefix.IsValid() {
+		return fmt.Errorf("iplist: invalid prefix %s", prefix)
+	}
+	b.country.add(prefix, b.countries.id(code))
+	return nil
+}
+
+// AddSubdivision maps prefix to an ISO 3166-2 subdivision code such as
+// GB-ABE or TH-11. Every subdivision range must lie within a range of its
+// parent country (the code's first two letters); this is checked on write.
+func (b *Builder) AddSubdivision(prefix netip.Prefix, code string) error {
+	if !validSubdivisionCode(code) {
+		return fmt.Errorf("iplist: invalid subdivision code %q", code)
+	}
+	if !prefix.IsValid() {
+		return fmt.Errorf("iplist: invalid prefix %s", prefix)
+	}
+	b.subdiv.add(prefix, b.subdivs.id(code))
+	return nil
+}
+
+// validSubdivisionCode reports whether code looks like an ISO 3166-2 code:
+// a two-letter country code, a hyphen and one to three letters or digits.
+func validSubdivisionCode(code string) bool {
+	cc, sub, ok := strings.Cut(code, "-")
+	if !ok || len(cc) != 2 || len(sub) < 1 || len(sub) > 3 {
+		return false
+	}
+	for i := 0; i < len(cc); i++ {
+		if cc[i] < 'A' || cc[i] > 'Z' {
+			return false
+		}
+	}
+	for i := 0; i < len(sub); i++ {
+		c := sub[i]
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// SetSubdivisionName sets the name written for an ISO 3166-2 subdivision
+// code. Subdivisions without a name are written with an empty one.
+func (b *Builder) SetSubdivisionName(code, name string) error {
+	if !validSubdivisionCode(code) {
+		return fmt.Errorf("iplist: invalid subdivision code %q", code)
+	}
+	if b.subdivNames == nil {
+		b.subdivNames = make(map[string]string)
+	}
+	b.subdivNames[code] = name
+	return nil
+}
+
+// subdivisionNamesFile lists subdivision names in a data directory; it is
+// written by the gulp city task next to the subdivision files.
+const subdivisionNamesFile = "country/subdivisions.tsv"
+
+// LoadSubdivisionNames reads subdivision names as tab-separated lines
+//
+//	code<TAB>name
+//
+// Empty lines and lines starting with # are ignored.
+func (b *Builder) LoadSubdivisionNames(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimRight(s.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		code, name, ok := strings.Cut(line, "\t")
+		if !ok {
+			return fmt.Errorf("iplist: subdivision names line %d: missing name", n)
+		}
+		if err := b.SetSubdivisionName(strings.TrimSpace(code), strings.TrimSpace(name)); err != nil {
+			return fmt.Errorf("iplist: subdivision names line %d: %w", n, err)
+		}
+	}
+	return s.Err()
+}
+
+// AddCNRegion maps prefix to a 6-digit CN admin code. Codes ending in 0000
+// are provinces, other codes ending in 00 are cities and the rest are
+// counties/districts.
+func (b *Builder) AddCNRegion(prefix netip.Prefix, code string) error {
+	if len(code) != 6 || strings.Trim(code, "0123456789") != "" {
+		return fmt.Errorf("iplist: invalid CN region code %q", code)
+	}
+	if !prefix.IsValid() {
+		return fmt.Errorf("iplist: invalid prefix %s", prefix)
+	}
+	b.cn.add(prefix, b.cnRegions.id(code))
+	return nil
+}
+
+// AddProvider maps prefix to a provider. An empty name or ProviderKindUnknown
+// selects the registered (see SetProviderInfo) or built-in name and kind for
+// key. All ranges of a key must use the same name and kind.
+func (b *Builder) AddProvider(prefix netip.Prefix, key, name string, kind ProviderKind) error {
+	if key == "" {
+		return fmt.Errorf("iplist: empty provider key")
+	}
+	if !prefix.IsValid() {
+		return fmt.Errorf("iplist: invalid prefix %s", prefix)
+	}
+	id := b.providers.id(key)
+	if int(id) == len(b.providerInfo) {
+		b.providerInfo = append(b.providerInfo, providerInfo{name: name, kind: kind})
+	} else if info := b.providerInfo[id]; info.name != name || info.kind != kind {
+		return fmt.Errorf("iplist: provider %q added with different name or kind", key)
+	}
+	b.provider.add(prefix, id)
+	return nil
+}
+
+// AddSpecial adds prefix to the named special set (see SpecialChina).
+// Special sets are independent of the country table and may overlap each
+// other; at most 32 sets are supported.
+func (b *Builder) AddSpecial(prefix netip.Prefix, set string) error {
+	if set == "" {
+		return fmt.Errorf("iplist: empty special set name")
+	}
+	if !prefix.IsValid() {
+		return fmt.Errorf("iplist: invalid prefix %s", prefix)
+	}
+	if _, ok := b.specials.idx[set]; !ok && len(b.specials.keys) == maxSpecialSets {
+		return fmt.Errorf("iplist: too many special sets (max %d)", maxSpecialSets)
+	}
+	b.special.add(prefix, b.specials.id(set))
+	return nil
+}
+
+// LoadFS adds the ranges of a repository-style data directory:
+//
+//   - country/*.txt (ISO 3166-1 alpha-2)
+//   - country/XX/XX-YY.txt (ISO 3166-2 subdivisions of country XX)
+//   - country/subdivisions.tsv (subdivision names, see LoadSubdivisionNames)
+//   - cncity/*.txt (CN admin code, 6 digits; province, city or district)
+//   - isp/*.txt (provider key)
+//   - special/*.txt (special set name, e.g. china)
+//   - providers.tsv (provider registry, see LoadProviderRegistry)
+//   - <category>/*.txt for any other directory (custom category, see
+//     AddCategory; the file name is the label)
+//
+// Each file lists one CIDR per line and may mix IPv4 and IPv6.
+func (b *Builder) LoadFS(fsys fs.FS) error {
+	if f, err := fsys.Open(ProviderRegistryFile); err == nil {
+		err = b.LoadProviderRegistry(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", ProviderRegistryFile, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	files, _ := fs.Glob(fsys, "country/*.txt")
+	for _, p := range files {
+		code := strings.TrimSuffix(path.Base(p), ".txt")
+		if len(code) != 2 {
+			continue
+		}
+		if err := readCIDRFile(fsys, p, func(pfx netip.Prefix) error { return b.AddCountry(pfx, code) }); err != nil {
+			return err
+		}
+		b.noteSource("country", code, p)
+	}
+
+	files, _ = fs.Glob(fsys, "country/*/*.txt")
+	for _, p := range files {
+		code := strings.TrimSuffix(path.Base(p), ".txt")
+		if parent := path.Base(path.Dir(p)); !strings.HasPrefix(code, parent+"-") {
+			return fmt.Errorf("%s: subdivision %q does not belong to country %q", p, code, parent)
+		}
+		if err := readCIDRFile(fsys, p, func(pfx netip.Prefix) error { return b.AddSubdivision(pfx, code) }); err != nil {
+			return err
+		}
+		b.noteSource("subdivision", code, p)
+	}
+	if f, err := fsys.Open(subdivisionNamesFile); err == nil {
+		err = b.LoadSubdivisionNames(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", subdivisionNamesFile, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	files, _ = fs.Glob(fsys, "cncity/*.txt")
+	for _, p := range files {
+		code := strings.TrimSuffix(path.Base(p), ".txt")
+		if len(code) != 6 {
+			continue
+		}
+		if err := readCIDRFile(fsys, p, func(pfx netip.Prefix) error { return b.AddCNRegion(pfx, code) }); err != nil {
+			return err
+		}
+		b.noteSource("cn", code, p)
+	}
+
+	files, _ = fs.Glob(fsys, "isp/*.txt")
+	for _, p := range files {
+		key := strings.TrimSuffix(path.Base(p), ".txt")
+		if err := readCIDRFile(fsys, p, func(pfx netip.Prefix) error { return b.AddProvider(pfx, key, "", ProviderKindUnknown) }); err != nil {
+			return err
+		}
+	}
+
+	files, _ = fs.Glob(fsys, "special/*.txt")
+	for _, p := range files {
+		set := strings.TrimSuffix(path.Base(p), ".txt")
+		if err := readCIDRFile(fsys, p, func(pfx netip.Prefix) error { return b.AddSpecial(pfx, set) }); err != nil {
+			return err
+		}
+	}
+
+	dirs, _ := fs.ReadDir(fsys, ".")
+	for _, d := range dirs {
+		category := d.Name()
+		if !d.IsDir() || reservedCategories[category] || strings.HasPrefix(category, ".") {
+			continue
+		}
+		files, _ = fs.Glob(fsys, category+"/*.txt")
+		for _, p := range files {
+			label := strings.TrimSuffix(path.Base(p), ".txt")
+			if err := readCIDRFile(fsys, p, func(pfx netip.Prefix) error { return b.AddCategory(pfx, category, label) }); err != nil {
+				return fmt.Errorf("%s: %w", p, err)
+			}
+			b.noteSource(category, label, p)
+		}
+	}
+	return nil
+}
+
+// WriteTo encodes the database and writes it to w.
+func (b *Builder) WriteTo(w io.Writer) (int64, error) {
+	out, err := b.encode()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(out)
+	return int64(n), err
+}
+
+func (b *Builder) encode() ([]byte, error) {
+	if b.cfg.signingKey != nil && len(b.cfg.signingKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("iplist: bad ed25519 private key length %d", len(b.cfg.signingKey))
+	}
+
+	buildTime, err := b.cfg.resolveBuildTime()
+	if err != nil {
+		return nil, err
+	}
+
+	// Labels are numbered in key order and strings are interned in label
+	// order, so the output does not depend on the order ranges were added in.
+	strIndex := newStringInterner()
+	countryOrder, countryIDs := b.countries.sortedKeys()
+	countryLabels := make([]label2, len(countryOrder))
+	for i, code := range countryOrder {
+		name, _ := docsCountryName(code)
+		if name == "" {
+			name = code
+		}
+		countryLabels[i] = label2{Code: strIndex.intern(code), Name: strIndex.intern(name)}
+	}
+	subdivOrder, subdivIDs := b.subdivs.sortedKeys()
+	subdivLabels := make([]label2, len(subdivOrder))
+	for i, code := range subdivOrder {
+		subdivLabels[i] = label2{Code: strIndex.intern(code), Name: strIndex.intern(b.subdivNames[code])}
+	}
+	cnMigrations, err := effectiveCNMigrations(b.cnMigrations, buildTime)
+	if err != nil {
+		return nil, err
+	}
+	cnOrder, cnIDs := b.cnRegions.migrated(cnMigrations)
+	cnLabels := make([]label2, len(cnOrder))
+	for i, code := range cnOrder {
+		name, _ := docsCNCityName(code)
+		if name == "" {
+			name = code
+		}
+		cnLabels[i] = label2{Code: strIndex.intern(code), Name: strIndex.intern(name)}
+	}
+	providerOrder, providerIDs := b.providers.sortedKeys()
+	providerLabels := make([]providerLabel, len(providerOrder))
+	providerRecords := make([]providerRecord, len(providerOrder))
+	for i, key := range providerOrder {
+		name, kind, rec := b.resolveProvider(key)
+		providerLabels[i] = providerLabel{Key: strIndex.intern(key), Name: strIndex.intern(name), Kind: uint32(kind)}
+		providerRecords[i] = rec
+	}
+	providerInfoBlob, err := encodeProviderRecords(providerRecords)
+	if err != nil {
+		return nil, err
+	}
+
+	specialOrder, specialIDs := b.specials.sortedKeys()
+	specialLabels := make([]uint32, len(specialOrder))
+	for i, set := range specialOrder {
+		specialLabels[i] = strIndex.intern(set)
+	}
+
+	countryEntries, countryEntries6 := b.country.sorted(countryIDs)
+	subdivEntries, subdivEntries6 := b.subdiv.sorted(subdivIDs)
+	// The level follows the (possibly migrated) code.
+	var cnProv, cnCity, cnDist rangeTable
+	for _, lt := range []struct {
+		level string
+		t     *rangeTable
+	}{{"cn_province", &cnProv}, {"cn_city", &cnCity}, {"cn_district", &cnDist}} {
+		for _, e := range b.cn.v4 {
+			if e.Label = cnIDs[e.Label]; cnLevel(cnOrder[e.Label]) == lt.level {
+				lt.t.v4 = append(lt.t.v4, e)
+			}
+		}
+		for _, e := range b.cn.v6 {
+			if e.Label = cnIDs[e.Label]; cnLevel(cnOrder[e.Label]) == lt.level {
+				lt.t.v6 = append(lt.t.v6, e)
+			}
+		}
+	}
+	cnProvEntries, cnProvEntries6 := cnProv.sorted(nil)
+	cnCityEntries, cnCityEntries6 := cnCity.sorted(nil)
+	cnDistEntries, cnDistEntries6 := cnDist.sorted(nil)
+	var providerSets providerSetWriter
+	providerEntries, providerEntries6 := b.provider.sorted(providerIDs)
+	providerEntries, providerMulti := segmentProviders(providerEntries, &providerSets)
+	providerEntries6, providerMulti6 := segmentProviders6(providerEntries6, &providerSets)
+	specialEntries, specialEntries6 := b.special.sorted(specialIDs)
+	specialEntries = segmentMasks(specialEntries)
+	specialEntries6 = segmentMasks6(specialEntries6)
+
+	// Resolve overlaps between different labels according to the policy.
+	countryFiles := b.labelFiles("country", &b.countries, countryIDs, len(countryOrder))
+	subdivFiles := b.labelFiles("subdivision", &b.subdivs, subdivIDs, len(subdivOrder))
+	cnFiles := b.labelFiles("cn", &b.cnRegions, cnIDs, len(cnOrder))
+	for _, t := range []struct {
+		category string
+		names    []string
+		files    [][]string
+		v4       *[]entry
+		v6       *[]entry6
+	}{
+		{"country", countryOrder, countryFiles, &countryEntries, &countryEntries6},
+		{"subdivision", subdivOrder, subdivFiles, &subdivEntries, &subdivEntries6},
+		{"cn_province", cnOrder, cnFiles, &cnProvEntries, &cnProvEntries6},
+		{"cn_city", cnOrder, cnFiles, &cnCityEntries, &cnCityEntries6},
+		{"cn_district", cnOrder, cnFiles, &cnDistEntries, &cnDistEntries6},
+	} {
+		r := conflictResolver{cfg: &b.cfg, category: t.category, names: t.names, files: t.files}
+		*t.v4 = r.resolve4(*t.v4)
+		*t.v6 = r.resolve6(*t.v6)
+		if err := r.err(); err != nil {
+			return nil, err
+		}
+	}
+
+	categoryNames := make([]string, 0, len(b.categories))
+	for name := range b.categories {
+		categoryNames = append(categoryNames, name)
+	}
+	sort.Strings(categoryNames)
+	categoryRecords := make([]categoryRecord, len(categoryNames))
+	categoryLabels := make([][]uint32, len(categoryNames))
+	categoryEntries := make([][]entry, len(categoryNames))
+	categoryEntries6 := make([][]entry6, len(categoryNames))
+	for i, name := range categoryNames {
+		c := b.categories[name]
+		order, ids := c.labels.sortedKeys()
+		categoryRecords[i] = categoryRecord{Table: uint32(tableCustomBase) + uint32(i), Name: strIndex.intern(name)}
+		categoryLabels[i] = make([]uint32, len(order))
+		for j, l := range order {
+			categoryLabels[i][j] = strIndex.intern(l)
+		}
+		v4, v6 := c.ranges.sorted(ids)
+		r := conflictResolver{cfg: &b.cfg, category: name, names: order, files: b.labelFiles(name, &c.labels, ids, len(order))}
+		categoryEntries[i], categoryEntries6[i] = r.resolve4(v4), r.resolve6(v6)
+		if err := r.err(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Subdivision ranges must lie within their parent country.
+	sc := newSubdivisionCheck(&conflictResolver{cfg: &b.cfg, category: "subdivision", names: subdivOrder, files: subdivFiles,
+		problem: "subdivision ranges outside their country"}, countryOrder, countryFiles)
+	subdivEntries, subdivEntries6 = sc.resolve4(subdivEntries, countryEntries), sc.resolve6(subdivEntries6, countryEntries6)
+	if err := sc.r.err(); err != nil {
+		return nil, err
+	}
+
+	// Note: we intentionally do not densify ranges by default.
+	// Densifying (filling gaps) can significantly increase the number of entries,
+	// which hurts cache locality and makes binary search slower on this dataset.
+
+	// Every section is 8-byte aligned for safe unsafe.Slice on strict-alignment arches.
+	w := newSectionWriter()
+	if err := w.add(secStrings, 0, 0, strIndex.encode()); err != nil {
+		return nil, err
+	}
+	if err := w.add(secCountryLabels, 0, 0, countryLabels); err != nil {
+		return nil, err
+	}
+	if err := w.add(secCNLabels, 0, 0, cnLabels); err != nil {
+		return nil, err
+	}
+	if err := w.add(secProviderLabels, 0, 0, providerLabels); err != nil {
+		return nil, err
+	}
+	if err := w.add(secSubdivLabels, 0, 0, subdivLabels); err != nil {
+		return nil, err
+	}
+	if err := w.add(secSpecialLabels, 0, 0, specialLabels); err != nil {
+		return nil, err
+	}
+	if err := w.add(secCNMigrations, 0, 0, cnMigrations); err != nil {
+		return nil, err
+	}
+	if err := w.add(secProviderInfo, 0, 0, providerInfoBlob); err != nil {
+		return nil, err
+	}
+	if err := w.add(secProviderSets, 0, 0, providerSets.data); err != nil {
+		return nil, err
+	}
+	if err := w.add(secCategories, 0, 0, categoryRecords); err != nil {
+		return nil, err
+	}
+	for i, labels := range categoryLabels {
+		if err := w.add(secCategoryLabels, tableCustomBase+uint16(i), 0, labels); err != nil {
+			return nil, err
+		}
+	}
+	providerSetKeys := func(off uint32) []uint32 {
+		n := providerSets.data[off]
+		return providerSets.data[off+1 : off+1+n]
+	}
+	tables := []struct {
+		id   uint16
+		v4   []entry
+		v6   []entry6
+		post *postingKeys
+	}{
+		{tableCountry, countryEntries, countryEntries6, &postingKeys{n: len(countryOrder)}},
+		{tableCNProv, cnProvEntries, cnProvEntries6, &postingKeys{n: len(cnOrder)}},
+		{tableCNCity, cnCityEntries, cnCityEntries6, &postingKeys{n: len(cnOrder)}},
+		{tableCNDist, cnDistEntries, cnDistEntries6, &postingKeys{n: len(cnOrder)}},
+		{tableProvider, providerEntries, providerEntries6, &postingKeys{n: len(providerOrder)}},
+		{tableSubdiv, subdivEntries, subdivEntries6, &postingKeys{n: len(subdivOrder)}},
+		{tableSpecial, specialEntries, specialEntries6, nil},
+		{tableProviderMulti, providerMulti, providerMulti6, &postingKeys{n: len(providerOrder), keys: providerSetKeys}},
+	}
+	for i := range categoryNames {
+		tables = append(tables, struct {
+			id   uint16
+			v4   []entry
+			v6   []entry6
+			post *postingKeys
+		}{tableCustomBase + uint16(i), categoryEntries[i], categoryEntries6[i], &postingKeys{n: len(categoryLabels[i])}})
+	}
+	for _, t := range tables {
+		if err := w.addTable4(t.id, t.v4, t.post); err != nil {
+			return nil, err
+		}
+		if err := w.addTable6(t.id, t.v6, t.post); err != nil {
+			return nil, err
+		}
+	}
+
+	version := b.cfg.builderVer
+	if version == "" {
+		version = builderVersion()
+	}
+	md := Metadata{
+		Sources:        b.cfg.sources,
+		DataRevision:   b.cfg.dataRevision,
+		BuilderVersion: version,
+		Counts: map[string]CategoryCount{
+			"country":     countCategory(countryEntries, countryEntries6),
+			"cn_province": countCategory(cnProvEntries, cnProvEntries6),
+			"cn_city":     countCategory(cnCityEntries, cnCityEntries6),
+			"cn_district": countCategory(cnDistEntries, cnDistEntries6),
+			"provider":    countCategory(providerEntries, providerEntries6),
+			"subdivision": countCategory(subdivEntries, subdivEntries6),
+			"special": {
+				Ranges4: len(specialEntries),
+				Ranges6: len(specialEntries6),
+				Labels:  len(specialLabels),
+			},
+		},
+		Extra: b.cfg.extra,
+	}
+	for i, name := range categoryNames {
+		md.Counts[name] = countCategory(categoryEntries[i], categoryEntries6[i])
+	}
+	mdBlob, err := encodeMetadata(&md)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.add(secMetadata, 0, 0, mdBlob); err != nil {
+		return nil, err
+	}
+
+	return w.finish(buildTime.Unix(), b.cfg.signingKey)
+}
+
+func u32Addr(v uint32) netip.Addr {
+	return netip.AddrFrom4([4]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)})
+}
+
+// labelSet assigns dense ids to label keys in insertion order.
+type labelSet struct {
+	idx  map[string]uint32
+	keys []string
+}
+
+func (s *labelSet) id(key string) uint32 {
+	if i, ok := s.idx[key]; ok {
+		return i
+	}
+	if s.idx == nil {
+		s.idx = make(map[string]uint32)
+	}
+	i := uint32(len(s.keys))
+	s.idx[key] = i
+	s.keys = append(s.keys, key)
+	return i
+}
+
+// sortedKeys returns the keys in ascending order and, indexed by insertion
+// id, the position of each key in that order.
+func (s *labelSet) sortedKeys() ([]string, []uint32) {
+	keys := slices.Clone(s.keys)
+	slices.Sort(keys)
+	ids := make([]uint32, len(s.keys))
+	for i, k := range keys {
+		ids[s.idx[k]] = uint32(i)
+	}
+	return keys, ids
+}
+
+// labelFiles returns the files LoadFS read each label of set from, with
+// labels renumbered through ids. n is the number of labels.
+func (b *Builder) labelFiles(category string, set *labelSet, ids []uint32, n int) [][]string {
+	if len(b.sources) == 0 {
+		return nil
+	}
+	files := make([][]string, n)
+	for i, k := range set.keys {
+		if f, ok := b.sources[category+"\x00"+k]; ok {
+			files[ids[i]] = append(files[ids[i]], f)
+		}
+	}
+	for _, f := range files {
+		sort.Strings(f)
+	}
+	return files
+}
+
+// noteSource records that category/key was read from file.
+func (b *Builder) noteSource(category, key, file string) {
+	if b.sources == nil {
+		b.sources = make(map[string]string)
+	}
+	b.sources[category+"\x00"+key] = file
+}
+
+// migrated is like sortedKeys but first replaces every key by its current
+// code under migs; keys that end up equal share one id.
+func (s *labelSet) migrated(migs []cnMigration) ([]string, []uint32) {
+	var cur labelSet
+	remap := make([]uint32, len(s.keys))
+	for i, k := range s.keys {
+		if c, err := parseCNCode(k); err == nil {
+			if c, ok := resolveCNMigration(migs, c); ok {
+				k = formatCNCode(c)
+			}
+		}
+		remap[i] = cur.id(k)
+	}
+	keys, ids := cur.sortedKeys()
+	for i, id := range remap {
+		remap[i] = ids[id]
+	}
+	return keys, remap
+}
+
+// rangeTable collects the ranges of one category before they are merged.
+type rangeTable struct {
+	v4 []entry
+	v6 []entry6
+}
+
+// add records p under label. IPv4-mapped prefixes (::ffff:a.b.c.d/96 and
+// longer) are stored as the IPv4 prefix they map to, since lookups of
+// mapped addresses go to the IPv4 tables.
+func (t *rangeTable) add(p netip.Prefix, label uint32) {
+	p = p.Masked()
+	if p.Addr().Is4In6() && p.Bits() >= 96 {
+		p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+	}
+	if p.Addr().Is4() {
+		ip4 := p.Addr().As4()
+		start := uint32(ip4[0])<<24 | uint32(ip4[1])<<16 | uint32(ip4[2])<<8 | uint32(ip4[3])
+		end := start | uint32(uint64(1)<<(32-p.Bits())-1)
+		t.v4 = append(t.v4, entry{Start: start, End: end, Label: label})
+		return
+	}
+	start := u128FromAddr(p.Addr())
+	t.v6 = append(t.v6, entry6{Start: start, End: start.or(hostMask(128 - p.Bits())), Label: label})
+}
+
+// sorted renumbers labels through ids (unless nil), merges overlapping and adjacent ranges
+// of the same label and returns the result ordered by start address.
+// The table itself is left unchanged.
+func (t *rangeTable) sorted(ids []uint32) ([]entry, []entry6) {
+	v4 := make([]entry, len(t.v4))
+	for i, e := range t.v4 {
+		if ids != nil {
+			e.Label = ids[e.Label]
+		}
+		v4[i] = e
+	}
+	sort.Slice(v4, func(i, j int) bool {
+		if v4[i].Label != v4[j].Label {
+			return v4[i].Label < v4[j].Label
+		}
+		return v4[i].Start < v4[j].Start
+	})
+	merged := v4[:0]
+	for _, cur := range v4 {
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			if last.Label == cur.Label && (cur.Start <= last.End || cur.Start == last.End+1) {
+				last.End = max(last.End, cur.End)
+				continue
+			}
+		}
+		merged = append(merged, cur)
+	}
+	v4 = merged
+	sort.Slice(v4, func(i, j int) bool { return v4[i].Start < v4[j].Start })
+
+	v6 := make([]entry6, len(t.v6))
+	for i, e := range t.v6 {
+		if ids != nil {
+			e.Label = ids[e.Label]
+		}
+		v6[i] = e
+	}
+	sort.Slice(v6, func(i, j int) bool {
+		if v6[i].Label != v6[j].Label {
+			return v6[i].Label < v6[j].Label
+		}
+		return v6[i].Start.less(v6[j].Start)
+	})
+	merged6 := v6[:0]
+	for _, cur := range v6 {
+		if n := len(merged6); n > 0 {
+			last := &merged6[n-1]
+			if last.Label == cur.Label && (!last.End.less(cur.Start) || (!last.End.isMax() && last.End.addOne() == cur.Start)) {
+				if last.End.less(cur.End) {
+					last.End = cur.End
+				}
+				continue
+			}
+		}
+		merged6 = append(merged6, cur)
+	}
+	v6 = merged6
+	sortEntries6(v6)
+	return v4, v6
+}
+
+// readCIDRFile calls fn for every CIDR listed in name, one per line.
+func readCIDRFile(fsys fs.FS, name string, fn func(netip.Prefix) error) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewScanner(f)
+	for r.Scan() {
+		line := strings.TrimSpace(r.Text())
+		if line == "" {
+			continue
+		}
+		p, err := netip.ParsePrefix(line)
+		if err != nil {
+			return fmt.Errorf("%s: parse CIDR %q: %w", name, line, err)
+		}
+		if err := fn(p); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return r.Err()
+}
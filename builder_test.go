@@ -0,0 +1,200 @@
+package iplist
+
+import (
+	"bytes"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// testDBBytes returns the encoded database of b.
+func testDBBytes(t *testing.T, b *Builder) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// writeTestDB writes b to a file in a temporary directory and returns its path.
+func writeTestDB(t *testing.T, b *Builder) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "iplist.db")
+	if err := os.WriteFile(path, testDBBytes(t, b), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// buildTestDB encodes b and opens the result from memory.
+func buildTestDB(t *testing.T, b *Builder) *DB {
+	t.Helper()
+	db, err := OpenBytes(testDBBytes(t, b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestBuilderLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"country/CN.txt":           {Data: []byte("1.0.1.0/24\n1.0.2.0/23\n240e::/20\n")},
+		"country/US.txt":           {Data: []byte("8.8.8.0/24\n")},
+		"country/US/US-CA.txt":     {Data: []byte("8.8.8.0/25\n")},
+		"country/subdivisions.tsv": {Data: []byte("# code\tname\nUS-CA\tCalifornia\nUS-TX\tTexas\n")},
+		"cncity/110000.txt":        {Data: []byte("1.0.1.0/24\n")},
+		"cncity/440100.txt":        {Data: []byte("1.0.2.0/24\n")},
+		"isp/chinanet.txt":         {Data: []byte("1.0.1.0/24\n")},
+		"isp/cloudflare.txt":       {Data: []byte("2606:4700::/32\n")},
+	}
+	b := NewBuilder()
+	if err := b.LoadFS(fsys); err != nil {
+		t.Fatal(err)
+	}
+	db := buildTestDB(t, b)
+
+	tests := []struct {
+		ip       string
+		country  string
+		city     string
+		provider string
+	}{
+		{"1.0.1.1", "CN", "", "chinanet"},
+		{"1.0.2.1", "CN", "440100", ""},
+		{"1.0.3.255", "CN", "", ""},
+		{"8.8.8.8", "US", "", ""},
+		{"240e::1", "CN", "", ""},
+		{"2606:4700::1", "", "", "cloudflare"},
+	}
+	for _, tt := range tests {
+		res, _, err := db.Lookup(tt.ip)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.ip, err)
+		}
+		if res.CountryCode != tt.country || res.CNCityCode != tt.city || res.ProviderKey != tt.provider {
+			t.Errorf("%s: got country=%q city=%q provider=%q", tt.ip, res.CountryCode, res.CNCityCode, res.ProviderKey)
+		}
+	}
+	if res, _, _ := db.Lookup("8.8.8.8"); res.SubdivisionCode != "US-CA" || res.SubdivisionName != "California" {
+		t.Errorf("8.8.8.8: subdivision %q (%q)", res.SubdivisionCode, res.SubdivisionName)
+	}
+	if res, _, _ := db.Lookup("8.8.8.200"); res.SubdivisionCode != "" {
+		t.Errorf("8.8.8.200: subdivision %q", res.SubdivisionCode)
+	}
+	res, _, _ := db.Lookup("1.0.1.1")
+	if res.CNProvinceCode != "110000" {
+		t.Errorf("1.0.1.1: province %q", res.CNProvinceCode)
+	}
+	if res, _, _ := db.Lookup("2606:4700::1"); !res.ProviderKind.Has(ProviderKindCloud) {
+		t.Errorf("cloudflare kind %v", res.ProviderKind)
+	}
+}
+
+func TestBuilderAdd(t *testing.T) {
+	b := NewBuilder()
+	for _, p := range []string{"10.0.0.0/25", "10.0.0.128/25", "10.0.0.0/24"} {
+		if err := b.AddProvider(netip.MustParsePrefix(p), "corp", "Corp Net", ProviderKindISP); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.AddProvider(netip.MustParsePrefix("10.1.0.0/16"), "corp", "Other", ProviderKindISP); err == nil {
+		t.Error("expected error for conflicting provider name")
+	}
+	if err := b.AddCNRegion(netip.MustParsePrefix("10.0.0.0/24"), "1101"); err == nil {
+		t.Error("expected error for short code")
+	}
+	db := buildTestDB(t, b)
+
+	cidrs, kind, err := db.ProviderIPs("corp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cidrs) != 1 || cidrs[0] != "10.0.0.0/24" || kind != ProviderKindISP {
+		t.Errorf("got %v %v", cidrs, kind)
+	}
+	if res, _, _ := db.Lookup("10.0.0.200"); res.ProviderName != "Corp Net" {
+		t.Errorf("name %q", res.ProviderName)
+	}
+}
+
+func TestBuilderOverlap(t *testing.T) {
+	b := NewBuilder()
+	_ = b.AddCountry(netip.MustParsePrefix("10.0.0.0/8"), "AA")
+	_ = b.AddCountry(netip.MustParsePrefix("10.1.0.0/16"), "BB")
+	if _, err := b.WriteTo(&bytes.Buffer{}); err == nil {
+		t.Fatal("expected overlap error")
+	}
+}
+
+func TestBuilderSubdivisionParent(t *testing.T) {
+	b := NewBuilder()
+	_ = b.AddCountry(netip.MustParsePrefix("10.0.0.0/16"), "GB")
+	_ = b.AddCountry(netip.MustParsePrefix("10.1.0.0/16"), "TH")
+	if err := b.AddSubdivision(netip.MustParsePrefix("10.1.0.0/24"), "GB-ABE"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.WriteTo(&bytes.Buffer{}); err == nil {
+		t.Fatal("expected error for subdivision outside its country")
+	}
+	if err := b.AddSubdivision(netip.MustParsePrefix("10.0.0.0/24"), "gb-abe"); err == nil {
+		t.Fatal("expected error for malformed code")
+	}
+
+	// Other policies drop the stray parts and report them.
+	var got []Conflict
+	b = NewBuilder(WithConflictPolicy(ConflictMostSpecific), WithConflictReport(func(c Conflict) { got = append(got, c) }))
+	_ = b.AddCountry(netip.MustParsePrefix("10.0.0.0/16"), "GB")
+	_ = b.AddCountry(netip.MustParsePrefix("10.1.0.0/16"), "TH")
+	_ = b.AddCountry(netip.MustParsePrefix("2001:db8::/33"), "GB")
+	_ = b.AddSubdivision(netip.MustParsePrefix("10.0.255.0/24"), "GB-ABE")
+	_ = b.AddSubdivision(netip.MustParsePrefix("10.1.0.0/24"), "GB-ABE")
+	_ = b.AddSubdivision(netip.MustParsePrefix("2001:db8::/32"), "GB-ABE")
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	db, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ip, want := range map[string]string{"10.0.255.1": "GB-ABE", "10.1.0.1": "", "2001:db8::1": "GB-ABE", "2001:db8:8000::1": ""} {
+		if res, _, _ := db.Lookup(ip); res.SubdivisionCode != want {
+			t.Errorf("%s: subdivision %q, want %q", ip, res.SubdivisionCode, want)
+		}
+	}
+	if len(got) != 2 || !slices.Equal(got[0].Labels, []string{"GB-ABE", "TH"}) || !slices.Equal(got[1].Labels, []string{"GB-ABE"}) ||
+		got[1].Prefixes[0].String() != "2001:db8:8000::/33" {
+		t.Errorf("conflicts = %v", got)
+	}
+}
+
+func TestBuilderReproducible(t *testing.T) {
+	prefixes := []struct{ p, code string }{
+		{"1.0.0.0/24", "CN"}, {"8.8.8.0/24", "US"}, {"2001:db8::/32", "JP"}, {"9.9.9.0/24", "CH"},
+	}
+	encode := func(reverse bool) []byte {
+		b := NewBuilder(WithBuildTime(time.Unix(1700000000, 0)))
+		for i := range prefixes {
+			if reverse {
+				i = len(prefixes) - 1 - i
+			}
+			if err := b.AddCountry(netip.MustParsePrefix(prefixes[i].p), prefixes[i].code); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var buf bytes.Buffer
+		if _, err := b.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	if !bytes.Equal(encode(false), encode(true)) {
+		t.Fatal("output depends on insertion order")
+	}
+}
@@ -0,0 +1,256 @@
+package iplist
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+)
+
+// Custom categories are user-defined tables loaded from data/<category>/*.txt
+// (e.g. office, blocklist, partners); the file name is the label. Each one is
+// stored as a generic table with id tableCustomBase+i, i being the index of
+// the category in name order. secCategories names the tables and
+// secCategoryLabels (Table = table id) lists the label strings.
+
+const (
+	tableCustomBase uint16 = 64
+	maxCategories          = 1024
+)
+
+// categoryRecord is one entry of secCategories.
+type categoryRecord struct {
+	Table uint32
+	Name  uint32 // string id
+}
+
+// reservedCategories are names LoadFS reads as built-in tables or that are
+// used for them in conflict reports and metadata.
+var reservedCategories = map[string]bool{
+	"country": true, "subdivision": true, "cncity": true, "cn": true,
+	"cn_province": true, "cn_city": true, "cn_district": true,
+	"isp": true, "provider": true, "special": true,
+}
+
+// CategoryLabel is the label of a custom category an IP matched.
+type CategoryLabel struct {
+	Category string
+	Label    string
+}
+
+type customCategory struct {
+	name   string
+	labels []uint32 // string ids
+	t4     v4Table
+	t6     v6Table
+}
+
+type customBuild struct {
+	labels labelSet
+	ranges rangeTable
+}
+
+// validCategoryName reports whether name can be used for a custom category:
+// lower-case letters, digits, '-' and '_', not a built-in name.
+func validCategoryName(name string) bool {
+	if name == "" || reservedCategories[name] {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// AddCategory maps prefix to label in the custom category. Labels of a
+// category are subject to the ConflictPolicy like the built-in tables.
+func (b *Builder) AddCategory(prefix netip.Prefix, category, label string) error {
+	if !validCategoryName(category) {
+		return fmt.Errorf("iplist: invalid category name %q", category)
+	}
+	if label == "" {
+		return fmt.Errorf("iplist: empty label in category %q", category)
+	}
+	if !prefix.IsValid() {
+		return fmt.Errorf("iplist: invalid prefix %s", prefix)
+	}
+	c := b.categories[category]
+	if c == nil {
+		if len(b.categories) == maxCategories {
+			return fmt.Errorf("iplist: too many categories (max %d)", maxCategories)
+		}
+		if b.categories == nil {
+			b.categories = make(map[string]*customBuild)
+		}
+		c = &customBuild{}
+		b.categories[category] = c
+	}
+	c.ranges.add(prefix, c.labels.id(label))
+	return nil
+}
+
+// customTable returns the category with table id, growing
+// v.categories as needed, or nil if id is out of range.
+func (v *v4DB) customTable(id uint16) *customCategory {
+	if id < tableCustomBase || id >= tableCustomBase+maxCategories {
+		return nil
+	}
+	i := int(id - tableCustomBase)
+	if i >= len(v.categories) {
+		v.categories = append(v.categories, make([]customCategory, i+1-len(v.categories))...)
+	}
+	return &v.categories[i]
+}
+
+// initCategories names the custom tables and validates them.
+func (v *v4DB) initCategories() error {
+	if len(v.categoryRecords) != len(v.categories) {
+		return ErrInvalidDB
+	}
+	for _, r := range v.categoryRecords {
+		c := v.customTable(uint16(r.Table))
+		if c == nil || c.name != "" {
+			return ErrInvalidDB
+		}
+		if c.name = v.str(r.Name); c.name == "" {
+			return ErrInvalidDB
+		}
+	}
+	v.categoryByName = make(map[string]int, len(v.categories))
+	for i := range v.categories {
+		c := &v.categories[i]
+		t := &c.t4
+		if len(t.starts) != len(t.ends) || len(t.starts) != len(t.labels) ||
+			len(c.t6.starts) != len(c.t6.ends) || len(c.t6.starts) != len(c.t6.labels) {
+			return ErrInvalidDB
+		}
+		if err := t.initBuckets(); err != nil {
+			return err
+		}
+		v.categoryByName[c.name] = i
+	}
+	return nil
+}
+
+func (c *customCategory) label(v *v4DB, idx uint32) string {
+	if idx >= uint32(len(c.labels)) {
+		return ""
+	}
+	return v.str(c.labels[idx])
+}
+
+// lookupCategories4 appends the custom category matches of ip to dst.
+func (v *v4DB) lookupCategories4(ip uint32, dst []CategoryLabel) []CategoryLabel {
+	for i := range v.categories {
+		c := &v.categories[i]
+		if label, ok := c.t4.lookup(ip); ok {
+			dst = append(dst, CategoryLabel{Category: c.name, Label: c.label(v, label)})
+		}
+	}
+	return dst
+}
+
+func (v *v4DB) lookupCategories6(ip u128, dst []CategoryLabel) []CategoryLabel {
+	for i := range v.categories {
+		c := &v.categories[i]
+		if label, ok := c.t6.lookup(ip); ok {
+			dst = append(dst, CategoryLabel{Category: c.name, Label: c.label(v, label)})
+		}
+	}
+	return dst
+}
+
+// lookupCategoryIDs4 appends one label ID (or IDNone) per custom category.
+func (v *v4DB) lookupCategoryIDs4(ip uint32, dst []uint32) ([]uint32, bool) {
+	matched := false
+	for i := range v.categories {
+		label, ok := v.categories[i].t4.lookup(ip)
+		if !ok {
+			label = IDNone
+		}
+		matched = matched || ok
+		dst = append(dst, label)
+	}
+	return dst, matched
+}
+
+func (v *v4DB) lookupCategoryIDs6(ip u128, dst []uint32) ([]uint32, bool) {
+	matched := false
+	for i := range v.categories {
+		label, ok := v.categories[i].t6.lookup(ip)
+		if !ok {
+			label = IDNone
+		}
+		matched = matched || ok
+		dst = append(dst, label)
+	}
+	return dst, matched
+}
+
+// Categories returns the names of the custom categories in the order used by
+// Result.Categories and ResultIDs.CategoryIDs.
+func (db *DB) Categories() []string {
+	if db == nil || db.v4 == nil {
+		return nil
+	}
+	names := make([]string, len(db.v4.categories))
+	for i, c := range db.v4.categories {
+		names[i] = c.name
+	}
+	return names
+}
+
+// CategoryLabels returns the labels of a custom category; the index of a
+// label is its ID in ResultIDs.CategoryIDs.
+func (db *DB) CategoryLabels(name string) ([]string, error) {
+	if db == nil || db.v4 == nil {
+		return nil, ErrInvalidDB
+	}
+	i, ok := db.v4.categoryByName[name]
+	if !ok {
+		return nil, ErrUnknownCategory
+	}
+	c := &db.v4.categories[i]
+	labels := make([]string, len(c.labels))
+	for j := range labels {
+		labels[j] = c.label(db.v4, uint32(j))
+	}
+	return labels, nil
+}
+
+// LookupCategory returns the label addr has in the named custom category.
+func (db *DB) LookupCategory(addr netip.Addr, name string) (label string, ok bool, err error) {
+	if db == nil || db.v4 == nil {
+		return "", false, ErrInvalidDB
+	}
+	if !addr.IsValid() {
+		return "", false, ErrUnsupportedIP
+	}
+	i, found := db.v4.categoryByName[name]
+	if !found {
+		return "", false, ErrUnknownCategory
+	}
+	c := &db.v4.categories[i]
+	var idx uint32
+	if addr.Is4() || addr.Is4In6() {
+		ip4 := addr.As4()
+		idx, ok = c.t4.lookup(uint32(ip4[0])<<24 | uint32(ip4[1])<<16 | uint32(ip4[2])<<8 | uint32(ip4[3]))
+	} else {
+		idx, ok = c.t6.lookup(u128FromAddr(addr))
+	}
+	if !ok {
+		return "", false, nil
+	}
+	return c.label(db.v4, idx), true, nil
+}
+
+// Category returns the label of the named custom category in r, if any.
+func (r *Result) Category(name string) (string, bool) {
+	i := slices.IndexFunc(r.Categories, func(c CategoryLabel) bool { return c.Category == name })
+	if i < 0 {
+		return "", false
+	}
+	return r.Categories[i].Label, true
+}
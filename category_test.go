@@ -0,0 +1,57 @@
+package iplist
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCustomCategories(t *testing.T) {
+	fsys := fstest.MapFS{
+		"country/CN.txt":        {Data: []byte("10.0.0.0/8\n")},
+		"office/beijing.txt":    {Data: []byte("10.1.0.0/16\n2001:db8::/32\n")},
+		"office/shanghai.txt":   {Data: []byte("10.2.0.0/16\n")},
+		"blocklist/scanner.txt": {Data: []byte("10.1.2.0/24\n192.0.2.0/24\n")},
+	}
+	b := NewBuilder()
+	if err := b.LoadFS(fsys); err != nil {
+		t.Fatal(err)
+	}
+	db := buildTestDB(t, b)
+
+	if got := db.Categories(); !slices.Equal(got, []string{"blocklist", "office"}) {
+		t.Fatalf("Categories() = %v", got)
+	}
+	res, ok, _ := db.Lookup("10.1.2.3")
+	want := []CategoryLabel{{"blocklist", "scanner"}, {"office", "beijing"}}
+	if !ok || !slices.Equal(res.Categories, want) {
+		t.Errorf("10.1.2.3: categories %v", res.Categories)
+	}
+	if l, ok := res.Category("office"); !ok || l != "beijing" {
+		t.Errorf("Category(office) = %q %v", l, ok)
+	}
+	// Only a custom category matches.
+	if res, ok, _ := db.Lookup("192.0.2.1"); !ok || res.CountryCode != "" || len(res.Categories) != 1 {
+		t.Errorf("192.0.2.1: %v %+v", ok, res)
+	}
+	if l, ok, err := db.LookupCategory(netip.MustParseAddr("2001:db8::1"), "office"); err != nil || !ok || l != "beijing" {
+		t.Errorf("LookupCategory v6 = %q %v %v", l, ok, err)
+	}
+	if _, ok, err := db.LookupCategory(netip.MustParseAddr("10.3.0.1"), "office"); err != nil || ok {
+		t.Errorf("LookupCategory miss = %v %v", ok, err)
+	}
+	if _, _, err := db.LookupCategory(netip.MustParseAddr("10.1.0.1"), "nope"); err != ErrUnknownCategory {
+		t.Errorf("unknown category: %v", err)
+	}
+
+	ids, _, _ := db.LookupIDs("10.2.0.1")
+	labels, _ := db.CategoryLabels("office")
+	if len(ids.CategoryIDs) != 2 || ids.CategoryIDs[0] != IDNone || labels[ids.CategoryIDs[1]] != "shanghai" {
+		t.Errorf("CategoryIDs = %v", ids.CategoryIDs)
+	}
+
+	if err := NewBuilder().AddCategory(netip.MustParsePrefix("10.0.0.0/8"), "country", "x"); err == nil {
+		t.Error("reserved category name accepted")
+	}
+}
@@ -45,3 +45,25 @@ func rangeToCIDRs(start, end uint32) ([]netip.Prefix, error) {
 	}
 	return out, nil
 }
+
+func rangeToCIDRs6(start, end u128) ([]netip.Prefix, error) {
+	if end.less(start) {
+		return nil, nil
+	}
+	out := make([]netip.Prefix, 0, 8)
+	cur := start
+	for {
+		// Largest aligned block starting at cur that does not pass end.
+		host := cur.trailingZeros()
+		for host > 0 && end.less(cur.or(hostMask(host))) {
+			host--
+		}
+		out = append(out, netip.PrefixFrom(cur.addr(), 128-host))
+		last := cur.or(hostMask(host))
+		if !last.less(end) || last.isMax() {
+			break
+		}
+		cur = last.addOne()
+	}
+	return out, nil
+}
@@ -12,11 +12,11 @@ import (
 func exportCmd(args []string) {
 	fs := flag.NewFlagSet("export", flag.ExitOnError)
 	dbPath := fs.String("db", "iplist.db", "db file")
-	what := fs.String("what", "", "export table: country|cn_province|cn_city|provider")
+	what := fs.String("what", "", "export table: country|subdivision|cn_province|cn_city|cn_district|provider")
 	outPath := fs.String("out", "-", "output file ('-' for stdout)")
 	_ = fs.Parse(args)
 	if *what == "" {
-		fatal(fmt.Errorf("export: need -what country|cn_province|cn_city|provider"))
+		fatal(fmt.Errorf("export: need -what country|subdivision|cn_province|cn_city|cn_district|provider"))
 	}
 
 	db, err := iplist.Open(*dbPath)
@@ -34,10 +34,14 @@ func exportCmd(args []string) {
 	switch *what {
 	case "country":
 		err = db.ExportCountryTSV(w)
+	case "subdivision":
+		err = db.ExportSubdivisionTSV(w)
 	case "cn_province":
 		err = db.ExportCNProvinceTSV(w)
 	case "cn_city":
 		err = db.ExportCNCityTSV(w)
+	case "cn_district":
+		err = db.ExportCNDistrictTSV(w)
 	case "provider":
 		err = db.ExportProviderTSV(w)
 	default:
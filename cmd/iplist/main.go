@@ -1,9 +1,17 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/dnsoa/iplist"
 )
@@ -24,8 +32,20 @@ func main() {
 		cloudCmd(os.Args[2:])
 	case "provider":
 		providerCmd(os.Args[2:])
+	case "country":
+		countryCmd(os.Args[2:])
+	case "cnregion":
+		cnRegionCmd(os.Args[2:])
+	case "select":
+		selectCmd(os.Args[2:])
 	case "export":
 		exportCmd(os.Args[2:])
+	case "info":
+		infoCmd(os.Args[2:])
+	case "keygen":
+		keygenCmd(os.Args[2:])
+	case "verify":
+		verifyCmd(os.Args[2:])
 	default:
 		usage()
 		os.Exit(2)
@@ -34,24 +54,258 @@ func main() {
 
 func usage() {
 	fmt.Fprintln(os.Stderr, "usage:")
-	fmt.Fprintln(os.Stderr, "  iplist build   -data ./data -out ./iplist.db")
+	fmt.Fprintln(os.Stderr, "  iplist build   -data ./data -out ./iplist.db [-providers file] [-cn-migrations file] [-conflict policy] [-check]")
 	fmt.Fprintln(os.Stderr, "  iplist lookup  -db ./iplist.db 1.2.3.4")
 	fmt.Fprintln(os.Stderr, "  iplist cloud   -db ./iplist.db aliyun")
 	fmt.Fprintln(os.Stderr, "  iplist provider -db ./iplist.db chinatelecom")
-	fmt.Fprintln(os.Stderr, "  iplist export  -db ./iplist.db -what country|cn_province|cn_city|provider -out -")
+	fmt.Fprintln(os.Stderr, "  iplist country -db ./iplist.db CN")
+	fmt.Fprintln(os.Stderr, "  iplist cnregion -db ./iplist.db [-sub] 440000")
+	fmt.Fprintln(os.Stderr, "  iplist select  -db ./iplist.db 'country:CN & provider:chinatelecom - kind:cloud'")
+	fmt.Fprintln(os.Stderr, "  iplist export  -db ./iplist.db -what country|subdivision|cn_province|cn_city|cn_district|provider -out -")
+	fmt.Fprintln(os.Stderr, "  iplist info    -db ./iplist.db")
+	fmt.Fprintln(os.Stderr, "  iplist keygen  -out ./iplist")
+	fmt.Fprintln(os.Stderr, "  iplist verify  -db ./iplist.db [-pubkey ./iplist.pub]")
 }
 
 func buildCmd(args []string) {
 	fs := flag.NewFlagSet("build", flag.ExitOnError)
 	dataDir := fs.String("data", "data", "data directory")
 	out := fs.String("out", "iplist.db", "output db file")
+	sources := fs.String("sources", "OpenIPDB", "comma-separated upstream data set names")
+	rev := fs.String("rev", "", "data directory revision (default: last git commit of -data)")
+	keyPath := fs.String("sign-key", "", "PEM ed25519 private key to sign the db with")
+	check := fs.Bool("check", false, "verify that -out is what -data produces instead of writing it")
+	registry := fs.String("providers", "", "provider registry TSV; empty for none (default: "+iplist.DefaultProviderRegistry+" beside -data, if present)")
+	migrations := fs.String("cn-migrations", "", "TSV of CN admin code migrations (old, new, date); empty for none (default: "+iplist.DefaultCNMigrations+" beside -data, if present)")
+	conflict := fs.String("conflict", "fail", "overlap policy: fail|priority|most-specific|drop-both")
+	priority := fs.String("conflict-priority", "", "comma-separated labels for -conflict priority, highest first")
+	report := fs.String("conflict-report", "", "write the conflict report to this file (default stderr)")
+	var extra []string
+	fs.Func("meta", "extra metadata key=value (repeatable)", func(s string) error {
+		if !strings.Contains(s, "=") {
+			return fmt.Errorf("want key=value")
+		}
+		extra = append(extra, s)
+		return nil
+	})
 	_ = fs.Parse(args)
 
-	if err := iplist.Build(*dataDir, *out); err != nil {
+	policy, err := iplist.ParseConflictPolicy(*conflict)
+	if err != nil {
+		fatal(err)
+	}
+	var prio []string
+	if *priority != "" {
+		prio = strings.Split(*priority, ",")
+	}
+	reportOut := os.Stderr
+	if *report != "" {
+		f, err := os.Create(*report)
+		if err != nil {
+			fatal(err)
+		}
+		defer f.Close()
+		reportOut = f
+	}
+	opts := []iplist.BuildOption{
+		iplist.WithConflictPolicy(policy, prio...),
+		iplist.WithConflictReport(func(c iplist.Conflict) { fmt.Fprintln(reportOut, c) }),
+	}
+	if flagSet(fs, "providers") {
+		opts = append(opts, iplist.WithProviderRegistry(*registry))
+	}
+	if flagSet(fs, "cn-migrations") {
+		opts = append(opts, iplist.WithCNMigrations(*migrations))
+	}
+	if *keyPath != "" {
+		key, err := readPrivateKey(*keyPath)
+		if err != nil {
+			fatal(err)
+		}
+		opts = append(opts, iplist.WithSigningKey(key))
+	}
+	if *check {
+		if err := checkBuild(*dataDir, *out, opts); err != nil {
+			fatal(err)
+		}
+		fmt.Println("ok")
+		return
+	}
+
+	if *rev == "" {
+		*rev = gitRevision(*dataDir)
+	}
+	opts = append(opts, iplist.WithDataRevision(*rev))
+	if os.Getenv("SOURCE_DATE_EPOCH") == "" {
+		if t, ok := gitCommitTime(*dataDir); ok {
+			opts = append(opts, iplist.WithBuildTime(t))
+		}
+	}
+	for _, s := range strings.Split(*sources, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			opts = append(opts, iplist.WithSources(s))
+		}
+	}
+	for _, kv := range extra {
+		k, v, _ := strings.Cut(kv, "=")
+		opts = append(opts, iplist.WithMetadata(k, v))
+	}
+
+	if err := iplist.Build(*dataDir, *out, opts...); err != nil {
 		fatal(err)
 	}
 }
 
+// flagSet reports whether the named flag was given on the command line.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) { set = set || f.Name == name })
+	return set
+}
+
+// checkBuild rebuilds dataDir with the build time and metadata recorded in
+// dbPath and reports whether the result is byte-for-byte identical.
+// A signed file only matches when opts carries the same signing key.
+func checkBuild(dataDir, dbPath string, opts []iplist.BuildOption) error {
+	old, err := os.ReadFile(dbPath)
+	if err != nil {
+		return err
+	}
+	db, err := iplist.OpenBytes(old)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	md, err := db.Metadata()
+	if err != nil {
+		return err
+	}
+	opts = append(opts,
+		iplist.WithBuildTime(md.BuildTime),
+		iplist.WithBuilderVersion(md.BuilderVersion),
+		iplist.WithDataRevision(md.DataRevision),
+		iplist.WithSources(md.Sources...),
+	)
+	for k, v := range md.Extra {
+		opts = append(opts, iplist.WithMetadata(k, v))
+	}
+
+	tmp, err := os.MkdirTemp("", "iplist-check")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	out := filepath.Join(tmp, "iplist.db")
+	if err := iplist.Build(dataDir, out, opts...); err != nil {
+		return err
+	}
+	built, err := os.ReadFile(out)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(built, old) {
+		return nil
+	}
+
+	// Point at the categories that changed to make the mismatch actionable.
+	if fresh, err := iplist.OpenBytes(built); err == nil {
+		defer fresh.Close()
+		if got, err := fresh.Metadata(); err == nil {
+			for _, k := range sortedKeys(got.Counts) {
+				if c, o := got.Counts[k], md.Counts[k]; c != o {
+					fmt.Fprintf(os.Stderr, "%s: %s has ranges4=%d ranges6=%d labels=%d, data has ranges4=%d ranges6=%d labels=%d\n",
+						k, dbPath, o.Ranges4, o.Ranges6, o.Labels, c.Ranges4, c.Ranges6, c.Labels)
+				}
+			}
+		}
+	}
+	return fmt.Errorf("%s does not match %s", dbPath, dataDir)
+}
+
+// gitRevision returns the last commit that touched dir, or "" if dir is not
+// inside a git checkout. Unrelated commits do not change it, so rebuilding
+// unchanged data records the same revision. A "-dirty" suffix marks
+// uncommitted changes in dir.
+func gitRevision(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "log", "-1", "--format=%H", "--", ".").Output()
+	if err != nil || len(bytes.TrimSpace(out)) == 0 {
+		return ""
+	}
+	rev := strings.TrimSpace(string(out))
+	status, err := exec.Command("git", "-C", dir, "status", "--porcelain", "--", ".").Output()
+	if err == nil && len(bytes.TrimSpace(status)) > 0 {
+		rev += "-dirty"
+	}
+	return rev
+}
+
+// gitCommitTime returns the time of the last commit that touched dir, so that
+// rebuilding committed data stamps the same build time. It reports false
+// outside a git checkout or when dir has uncommitted changes.
+func gitCommitTime(dir string) (time.Time, bool) {
+	status, err := exec.Command("git", "-C", dir, "status", "--porcelain", "--", ".").Output()
+	if err != nil || len(bytes.TrimSpace(status)) > 0 {
+		return time.Time{}, false
+	}
+	out, err := exec.Command("git", "-C", dir, "log", "-1", "--format=%ct", "--", ".").Output()
+	if err != nil {
+		return time.Time{}, false
+	}
+	sec, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, 0).UTC(), true
+}
+
+func infoCmd(args []string) {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	dbPath := fs.String("db", "iplist.db", "db file")
+	_ = fs.Parse(args)
+
+	db, err := iplist.Open(*dbPath)
+	if err != nil {
+		fatal(err)
+	}
+	defer db.Close()
+
+	md, err := db.Metadata()
+	if err != nil {
+		fatal(err)
+	}
+	writeInfo(os.Stdout, md)
+}
+
+// writeInfo prints md as key=value lines, with counts and extra keys sorted.
+func writeInfo(w io.Writer, md iplist.Metadata) {
+	fmt.Fprintf(w, "build_time=%s\n", md.BuildTime.Format(time.RFC3339))
+	if len(md.Sources) > 0 {
+		fmt.Fprintf(w, "sources=%s\n", strings.Join(md.Sources, ","))
+	}
+	if md.DataRevision != "" {
+		fmt.Fprintf(w, "data_revision=%s\n", md.DataRevision)
+	}
+	if md.BuilderVersion != "" {
+		fmt.Fprintf(w, "builder_version=%s\n", md.BuilderVersion)
+	}
+	for _, name := range sortedKeys(md.Counts) {
+		c := md.Counts[name]
+		fmt.Fprintf(w, "%s: ranges4=%d ranges6=%d labels=%d\n", name, c.Ranges4, c.Ranges6, c.Labels)
+	}
+	for _, k := range sortedKeys(md.Extra) {
+		fmt.Fprintf(w, "meta.%s=%s\n", k, md.Extra[k])
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func lookupCmd(args []string) {
 	fs := flag.NewFlagSet("lookup", flag.ExitOnError)
 	dbPath := fs.String("db", "iplist.db", "db file")
@@ -60,7 +314,7 @@ func lookupCmd(args []string) {
 		fatal(fmt.Errorf("lookup: need 1 ip"))
 	}
 
-	db, err := iplist.Open(*dbPath)
+	db, err := iplist.Open(*dbPath, iplist.WithNetworks())
 	if err != nil {
 		fatal(err)
 	}
@@ -79,14 +333,29 @@ func lookupCmd(args []string) {
 	if res.CountryCode != "" {
 		fmt.Printf("country=%s (%s)\n", res.CountryCode, res.CountryName)
 	}
+	if res.SubdivisionCode != "" {
+		// Subdivisions carry no names yet.
+		fmt.Printf("subdivision=%s\n", res.SubdivisionCode)
+	}
+	if res.CNProvinceCode != "" {
+		fmt.Printf("cn_province=%s (%s)\n", res.CNProvinceCode, res.CNProvinceName)
+	}
 	if res.CNCityCode != "" {
 		fmt.Printf("cn_city=%s (%s)\n", res.CNCityCode, res.CNCityName)
-	} else if res.CNProvinceCode != "" {
-		fmt.Printf("cn_province=%s (%s)\n", res.CNProvinceCode, res.CNProvinceName)
+	}
+	if res.CNDistrictCode != "" {
+		fmt.Printf("cn_district=%s (%s)\n", res.CNDistrictCode, res.CNDistrictName)
 	}
 	if res.ProviderKey != "" {
-		fmt.Printf("provider=%s (%s) kind=%d\n", res.ProviderKey, res.ProviderName, res.ProviderKind)
+		fmt.Printf("provider=%s (%s) kind=%s\n", res.ProviderKey, res.ProviderName, res.ProviderKind)
+	}
+	if len(res.Special) > 0 {
+		fmt.Printf("special=%s\n", strings.Join(res.Special, ","))
 	}
+	for _, c := range res.Categories {
+		fmt.Printf("%s=%s\n", c.Category, c.Label)
+	}
+	fmt.Printf("network=%s\n", res.Network)
 }
 
 func cloudCmd(args []string) {
@@ -126,11 +395,76 @@ func providerCmd(args []string) {
 	}
 	defer db.Close()
 
-	cidrs, kind, err := db.ProviderIPs(fs.Arg(0))
+	if err := db.WriteProviderCIDRs(os.Stdout, fs.Arg(0)); err != nil {
+		fatal(err)
+	}
+}
+
+func countryCmd(args []string) {
+	fs := flag.NewFlagSet("country", flag.ExitOnError)
+	dbPath := fs.String("db", "iplist.db", "db file")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fatal(fmt.Errorf("country: need 1 country code, e.g. CN"))
+	}
+
+	db, err := iplist.Open(*dbPath)
+	if err != nil {
+		fatal(err)
+	}
+	defer db.Close()
+
+	cidrs, err := db.CountryIPs(fs.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+	for _, c := range cidrs {
+		fmt.Println(c)
+	}
+}
+
+func cnRegionCmd(args []string) {
+	fs := flag.NewFlagSet("cnregion", flag.ExitOnError)
+	dbPath := fs.String("db", "iplist.db", "db file")
+	sub := fs.Bool("sub", false, "include the ranges of cities and districts within the region")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fatal(fmt.Errorf("cnregion: need 1 admin code, e.g. 440000"))
+	}
+
+	db, err := iplist.Open(*dbPath)
+	if err != nil {
+		fatal(err)
+	}
+	defer db.Close()
+
+	cidrs, err := db.CNRegionIPs(fs.Arg(0), *sub)
+	if err != nil {
+		fatal(err)
+	}
+	for _, c := range cidrs {
+		fmt.Println(c)
+	}
+}
+
+func selectCmd(args []string) {
+	fs := flag.NewFlagSet("select", flag.ExitOnError)
+	dbPath := fs.String("db", "iplist.db", "db file")
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		fatal(fmt.Errorf("select: need an expression, e.g. 'cn:440000 - kind:cloud'"))
+	}
+
+	db, err := iplist.Open(*dbPath)
+	if err != nil {
+		fatal(err)
+	}
+	defer db.Close()
+
+	cidrs, err := db.Select(strings.Join(fs.Args(), " "))
 	if err != nil {
 		fatal(err)
 	}
-	_ = kind
 	for _, c := range cidrs {
 		fmt.Println(c)
 	}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/dnsoa/iplist"
+)
+
+func TestWriteInfo(t *testing.T) {
+	b := iplist.NewBuilder(
+		iplist.WithBuildTime(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)),
+		iplist.WithSources("OpenIPDB"),
+		iplist.WithDataRevision("abc123"),
+		iplist.WithBuilderVersion("iplist test"),
+		iplist.WithMetadata("channel", "nightly"),
+	)
+	_ = b.AddCountry(netip.MustParsePrefix("10.0.0.0/8"), "CN")
+	_ = b.AddCountry(netip.MustParsePrefix("2001:db8::/32"), "US")
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	db, err := iplist.OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	md, err := db.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	writeInfo(&out, md)
+	want := `build_time=2024-05-01T12:00:00Z
+sources=OpenIPDB
+data_revision=abc123
+builder_version=iplist test
+cn_city: ranges4=0 ranges6=0 labels=0
+cn_district: ranges4=0 ranges6=0 labels=0
+cn_province: ranges4=0 ranges6=0 labels=0
+country: ranges4=1 ranges6=1 labels=2
+provider: ranges4=0 ranges6=0 labels=0
+special: ranges4=0 ranges6=0 labels=0
+subdivision: ranges4=0 ranges6=0 labels=0
+meta.channel=nightly
+`
+	if out.String() != want {
+		t.Errorf("info output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,173 @@
+package iplist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CN admin codes change over time: cities are merged and counties upgraded.
+// A migration maps an obsolete code to its successor from an effective date
+// on. Build applies the migrations effective at build time to the data files
+// and stores them in the secCNMigrations section, so DB.ResolveCNCode can map
+// historical codes to the current region.
+
+// cnMigration is one entry of the secCNMigrations section.
+// Codes are stored as numbers, the date as yyyymmdd.
+type cnMigration struct {
+	Old  uint32
+	New  uint32
+	Date uint32
+}
+
+// AddCNMigration records that CN admin code oldCode was replaced by newCode
+// on the given date. Data for oldCode is built under newCode once the
+// migration is in effect at build time.
+func (b *Builder) AddCNMigration(oldCode, newCode string, effective time.Time) error {
+	o, err := parseCNCode(oldCode)
+	if err != nil {
+		return err
+	}
+	n, err := parseCNCode(newCode)
+	if err != nil {
+		return err
+	}
+	if o == n {
+		return fmt.Errorf("iplist: CN migration %s maps to itself", oldCode)
+	}
+	y, m, d := effective.Date()
+	b.cnMigrations = append(b.cnMigrations, cnMigration{Old: o, New: n, Date: uint32(y*10000 + int(m)*100 + d)})
+	return nil
+}
+
+// DefaultCNMigrations is the migration table Build applies unless
+// WithCNMigrations is given, relative to the parent of the data directory.
+// It lives outside the data directory because that is regenerated from
+// scratch.
+const DefaultCNMigrations = "src/plugins/cac/migrations.tsv"
+
+// WithCNMigrations makes Build apply the migration table in path (see
+// LoadCNMigrations) instead of DefaultCNMigrations; the file must exist. An
+// empty path applies no migrations. NewBuilder ignores it: call
+// LoadCNMigrations on the Builder instead.
+func WithCNMigrations(path string) BuildOption {
+	return func(c *buildConfig) { c.cnMigrations, c.cnMigrationsSet = path, true }
+}
+
+// LoadCNMigrations reads migrations as tab-separated lines
+//
+//	old_code<TAB>new_code<TAB>yyyy-mm-dd
+//
+// Empty lines and lines starting with # are ignored.
+func (b *Builder) LoadCNMigrations(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Split(line, "\t")
+		if len(f) != 3 {
+			return fmt.Errorf("iplist: CN migrations line %d: want 3 tab-separated fields", n)
+		}
+		date, err := time.Parse(time.DateOnly, strings.TrimSpace(f[2]))
+		if err != nil {
+			return fmt.Errorf("iplist: CN migrations line %d: %w", n, err)
+		}
+		if err := b.AddCNMigration(strings.TrimSpace(f[0]), strings.TrimSpace(f[1]), date); err != nil {
+			return fmt.Errorf("iplist: CN migrations line %d: %w", n, err)
+		}
+	}
+	return s.Err()
+}
+
+func parseCNCode(code string) (uint32, error) {
+	if len(code) != 6 || strings.Trim(code, "0123456789") != "" {
+		return 0, fmt.Errorf("iplist: invalid CN region code %q", code)
+	}
+	v, _ := strconv.ParseUint(code, 10, 32)
+	return uint32(v), nil
+}
+
+func formatCNCode(v uint32) string {
+	s := strconv.FormatUint(uint64(v), 10)
+	return strings.Repeat("0", 6-len(s)) + s
+}
+
+// effectiveCNMigrations returns the migrations in effect at t, sorted by old
+// code. A code migrated more than once keeps its latest migration.
+func effectiveCNMigrations(all []cnMigration, t time.Time) ([]cnMigration, error) {
+	y, m, d := t.UTC().Date()
+	today := uint32(y*10000 + int(m)*100 + d)
+	var out []cnMigration
+	for _, mg := range all {
+		if mg.Date <= today {
+			out = append(out, mg)
+		}
+	}
+	slices.SortFunc(out, func(a, b cnMigration) int {
+		if a.Old != b.Old {
+			return int(a.Old) - int(b.Old)
+		}
+		return int(a.Date) - int(b.Date)
+	})
+	// Keep the latest migration per old code.
+	dedup := out[:0]
+	for i, mg := range out {
+		if i+1 < len(out) && out[i+1].Old == mg.Old {
+			continue
+		}
+		dedup = append(dedup, mg)
+	}
+	out = dedup
+	for _, mg := range out {
+		if _, ok := resolveCNMigration(out, mg.Old); !ok {
+			return nil, fmt.Errorf("iplist: CN migrations form a cycle at %s", formatCNCode(mg.Old))
+		}
+	}
+	return out, nil
+}
+
+// resolveCNMigration follows migrations (sorted by Old) from code to the
+// current code. ok is false on a cycle.
+func resolveCNMigration(migs []cnMigration, code uint32) (uint32, bool) {
+	for range len(migs) + 1 {
+		i, found := slices.BinarySearchFunc(migs, code, func(m cnMigration, c uint32) int { return int(m.Old) - int(c) })
+		if !found {
+			return code, true
+		}
+		code = migs[i].New
+	}
+	return 0, false
+}
+
+// ResolveCNCode maps a CN admin code, possibly a historical one, to the
+// region it belongs to today according to the migrations stored at build
+// time. It returns the region's label ID (as used in ResultIDs), its current
+// code and name. ok is false if the resolved code has no label in the DB.
+func (db *DB) ResolveCNCode(code string) (id uint32, current, name string, ok bool) {
+	if db == nil || db.v4 == nil {
+		return IDNone, "", "", false
+	}
+	if id, ok = db.v4.resolveCNCode(code); !ok {
+		return IDNone, "", "", false
+	}
+	current, name = db.v4.cnLabel(id)
+	return id, current, name, true
+}
+
+func (v *v4DB) resolveCNCode(code string) (uint32, bool) {
+	c, err := parseCNCode(code)
+	if err != nil {
+		return IDNone, false
+	}
+	if c, ok := resolveCNMigration(v.cnMigrations, c); ok {
+		id, ok := v.cnByCode[formatCNCode(c)]
+		return id, ok
+	}
+	return IDNone, false
+}
@@ -0,0 +1,99 @@
+package iplist
+
+import (
+	"bytes"
+	"net/netip"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCNMigrations(t *testing.T) {
+	b := NewBuilder(WithBuildTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.0.0.0/16"), "370000")
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.0.0.0/24"), "370100")
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.0.1.0/24"), "371200")
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.0.2.0/24"), "371300")
+	err := b.LoadCNMigrations(bytes.NewBufferString("# comment\n" +
+		"371200\t370100\t2019-01-09\n" +
+		"371300\t370200\t2030-01-01\n" + // not yet in effect
+		"999900\t999800\t2000-01-01\n999800\t370100\t2001-01-01\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := buildTestDB(t, b)
+
+	for ip, want := range map[string]string{"10.0.1.1": "370100", "10.0.2.1": "371300"} {
+		if res, _, _ := db.Lookup(ip); res.CNCityCode != want {
+			t.Errorf("%s: city %q, want %q", ip, res.CNCityCode, want)
+		}
+	}
+	tests := []struct {
+		code, want string
+		ok         bool
+	}{
+		{"371200", "370100", true},
+		{"999900", "370100", true}, // chain
+		{"370100", "370100", true},
+		{"371300", "371300", true},
+		{"110000", "", false},
+		{"abc", "", false},
+	}
+	for _, tt := range tests {
+		id, cur, _, ok := db.ResolveCNCode(tt.code)
+		if cur != tt.want || ok != tt.ok {
+			t.Errorf("ResolveCNCode(%s) = %q %v", tt.code, cur, ok)
+		}
+		if ok {
+			if code, _, _ := db.CNByID(id); code != cur {
+				t.Errorf("ResolveCNCode(%s) id decodes to %q", tt.code, code)
+			}
+		}
+	}
+
+	c := NewBuilder()
+	_ = c.AddCNMigration("110103", "110101", time.Date(2010, 7, 1, 0, 0, 0, 0, time.UTC))
+	_ = c.AddCNMigration("110101", "110103", time.Date(2010, 7, 1, 0, 0, 0, 0, time.UTC))
+	if _, err := c.WriteTo(&bytes.Buffer{}); err == nil {
+		t.Error("migration cycle accepted")
+	}
+}
+
+// TestBuildCNMigrations checks how Build finds the migration table.
+func TestBuildCNMigrations(t *testing.T) {
+	root := writeDataDir(t, map[string]string{
+		"data/cncity/370100.txt": "10.0.0.0/24\n",
+		"data/cncity/371200.txt": "10.0.1.0/24\n",
+		DefaultCNMigrations:      "371200\t370100\t2019-01-09\n",
+		"other.tsv":              "# none\n",
+	})
+	dataDir := filepath.Join(root, "data")
+	tests := []struct {
+		name string
+		opts []BuildOption
+		want string // city of 10.0.1.1
+	}{
+		{"default", nil, "370100"},
+		{"explicit", []BuildOption{WithCNMigrations(filepath.Join(root, "other.tsv"))}, "371200"},
+		{"none", []BuildOption{WithCNMigrations("")}, "371200"},
+	}
+	for _, tt := range tests {
+		out := filepath.Join(t.TempDir(), "iplist.db")
+		if err := Build(dataDir, out, tt.opts...); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		db, err := Open(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res, _, _ := db.Lookup("10.0.1.1"); res.CNCityCode != tt.want {
+			t.Errorf("%s: city %q, want %q", tt.name, res.CNCityCode, tt.want)
+		}
+		db.Close()
+	}
+
+	out := filepath.Join(t.TempDir(), "iplist.db")
+	if err := Build(dataDir, out, WithCNMigrations(filepath.Join(root, "missing.tsv"))); err == nil {
+		t.Error("missing migration table accepted")
+	}
+}
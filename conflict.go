@@ -0,0 +1,487 @@
+package iplist
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ConflictPolicy decides what Build does when ranges with different labels
+// overlap within a category (country, subdivision or a CN level), and when
+// a subdivision range lies outside its parent country. Provider ranges may
+// always overlap, see providerset.go.
+type ConflictPolicy uint8
+
+const (
+	// ConflictFail aborts the build. This is the default.
+	ConflictFail ConflictPolicy = iota
+	// ConflictPriority keeps the label listed first in the priority list
+	// passed to WithConflictPolicy. Unlisted labels rank after listed ones,
+	// in code order.
+	ConflictPriority
+	// ConflictMostSpecific keeps the label whose range is smallest, i.e. the
+	// longest prefix. Equal ranges fall back to code order.
+	ConflictMostSpecific
+	// ConflictDropBoth leaves the overlapping addresses without a label.
+	ConflictDropBoth
+)
+
+func (p ConflictPolicy) String() string {
+	switch p {
+	case ConflictFail:
+		return "fail"
+	case ConflictPriority:
+		return "priority"
+	case ConflictMostSpecific:
+		return "most-specific"
+	case ConflictDropBoth:
+		return "drop-both"
+	}
+	return fmt.Sprintf("ConflictPolicy(%d)", uint8(p))
+}
+
+// ParseConflictPolicy parses the String form of a policy.
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	for p := ConflictFail; p <= ConflictDropBoth; p++ {
+		if p.String() == s {
+			return p, nil
+		}
+	}
+	return ConflictFail, fmt.Errorf("iplist: unknown conflict policy %q", s)
+}
+
+// Conflict describes addresses claimed by more than one label of a category.
+//
+// A subdivision range outside its parent country is reported with Category
+// subdivision and Labels holding the subdivision and the country the
+// addresses belong to (only the subdivision if none). Every policy other
+// than ConflictFail drops such addresses from the subdivision table.
+type Conflict struct {
+	Category string         // country, subdivision, cn_province, cn_city or cn_district
+	Labels   []string       // the conflicting codes
+	Files    []string       // data files of Labels, when loaded with LoadFS
+	Prefixes []netip.Prefix // the contested addresses
+	Policy   ConflictPolicy
+	Kept     string // label the addresses were assigned to; empty if dropped or failed
+}
+
+func (c Conflict) String() string {
+	var sb strings.Builder
+	sb.WriteString(c.Category)
+	sb.WriteString(": ")
+	sb.WriteString(strings.Join(c.Labels, " vs "))
+	if len(c.Files) > 0 {
+		sb.WriteString(" (")
+		sb.WriteString(strings.Join(c.Files, ", "))
+		sb.WriteString(")")
+	}
+	sb.WriteString(" at ")
+	for i, p := range c.Prefixes {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(p.String())
+	}
+	switch {
+	case c.Policy == ConflictFail:
+		sb.WriteString(": failed")
+	case c.Kept == "":
+		sb.WriteString(": dropped (" + c.Policy.String() + ")")
+	default:
+		sb.WriteString(": kept " + c.Kept + " (" + c.Policy.String() + ")")
+	}
+	return sb.String()
+}
+
+// WithConflictPolicy selects how overlapping ranges with different labels are
+// resolved. priority ranks labels for ConflictPriority, highest first.
+func WithConflictPolicy(policy ConflictPolicy, priority ...string) BuildOption {
+	return func(c *buildConfig) {
+		c.conflictPolicy = policy
+		c.conflictPriority = priority
+	}
+}
+
+// WithConflictReport calls fn for every conflict found while building,
+// whatever the policy.
+func WithConflictReport(fn func(Conflict)) BuildOption {
+	return func(c *buildConfig) { c.conflictReport = fn }
+}
+
+// sweep splits possibly overlapping ranges into elementary segments and calls
+// fn for each segment covered by at least one range, with the indexes of the
+// covering ranges. active is reused between calls.
+func sweep(in []entry, fn func(start, end uint32, active []int)) {
+	type event struct {
+		pos uint64
+		idx int
+		on  bool
+	}
+	events := make([]event, 0, 2*len(in))
+	for i, e := range in {
+		events = append(events, event{uint64(e.Start), i, true}, event{uint64(e.End) + 1, i, false})
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].pos < events[j].pos })
+
+	var active []int
+	for i := 0; i < len(events); {
+		pos := events[i].pos
+		for ; i < len(events) && events[i].pos == pos; i++ {
+			if events[i].on {
+				active = append(active, events[i].idx)
+			} else {
+				idx := events[i].idx
+				active = slices.DeleteFunc(active, func(x int) bool { return x == idx })
+			}
+		}
+		if len(active) == 0 || i == len(events) {
+			continue
+		}
+		fn(uint32(pos), uint32(events[i].pos-1), active)
+	}
+}
+
+// sweep6 is the IPv6 counterpart of sweep.
+func sweep6(in []entry6, fn func(start, end u128, active []int)) {
+	type event struct {
+		pos  u128
+		past bool // position is one past the last address
+		idx  int
+		on   bool
+	}
+	events := make([]event, 0, 2*len(in))
+	for i, e := range in {
+		events = append(events, event{pos: e.Start, idx: i, on: true})
+		if e.End.isMax() {
+			events = append(events, event{past: true, idx: i})
+		} else {
+			events = append(events, event{pos: e.End.addOne(), idx: i})
+		}
+	}
+	less := func(a, b event) bool {
+		if a.past != b.past {
+			return b.past
+		}
+		return a.pos.less(b.pos)
+	}
+	sort.Slice(events, func(i, j int) bool { return less(events[i], events[j]) })
+
+	var active []int
+	for i := 0; i < len(events); {
+		cur := events[i]
+		for ; i < len(events) && !less(cur, events[i]); i++ {
+			if events[i].on {
+				active = append(active, events[i].idx)
+			} else {
+				idx := events[i].idx
+				active = slices.DeleteFunc(active, func(x int) bool { return x == idx })
+			}
+		}
+		if len(active) == 0 || i == len(events) || cur.past {
+			continue
+		}
+		end := hostMask(128)
+		if !events[i].past {
+			end = events[i].pos.subOne()
+		}
+		fn(cur.pos, end, active)
+	}
+}
+
+// conflictResolver applies the build's ConflictPolicy to one category.
+type conflictResolver struct {
+	cfg      *buildConfig
+	category string
+	names    []string   // by label
+	files    [][]string // by label
+	problem  string     // for err; "overlapping ranges" if empty
+
+	count int
+	first string
+}
+
+// resolve4 returns in with all overlaps between different labels resolved.
+func (r *conflictResolver) resolve4(in []entry) []entry {
+	var out []entry
+	var pending *Conflict
+	var pendStart, pendEnd uint32
+	flush := func() {
+		if pending != nil {
+			pending.Prefixes, _ = rangeToCIDRs(pendStart, pendEnd)
+			r.report(*pending)
+			pending = nil
+		}
+	}
+	sweep(in, func(start, end uint32, active []int) {
+		if len(active) == 1 {
+			out = appendSegment(out, start, end, in[active[0]].Label)
+			return
+		}
+		labels := distinctLabels(in, active)
+		if len(labels) == 1 {
+			out = appendSegment(out, start, end, labels[0])
+			return
+		}
+		kept, ok := r.winner(labels, func(a, b uint32) bool {
+			return sizeOf(in, active, a) < sizeOf(in, active, b)
+		})
+		if ok {
+			out = appendSegment(out, start, end, kept)
+		}
+		c := r.conflict(labels, kept, ok)
+		if pending != nil && pendEnd+1 == start && slices.Equal(pending.Labels, c.Labels) && pending.Kept == c.Kept {
+			pendEnd = end
+			return
+		}
+		flush()
+		pending, pendStart, pendEnd = &c, start, end
+	})
+	flush()
+	return out
+}
+
+// resolve6 is the IPv6 counterpart of resolve4.
+func (r *conflictResolver) resolve6(in []entry6) []entry6 {
+	var out []entry6
+	var pending *Conflict
+	var pendStart, pendEnd u128
+	flush := func() {
+		if pending != nil {
+			pending.Prefixes, _ = rangeToCIDRs6(pendStart, pendEnd)
+			r.report(*pending)
+			pending = nil
+		}
+	}
+	sweep6(in, func(start, end u128, active []int) {
+		if len(active) == 1 {
+			out = appendSegment6(out, start, end, in[active[0]].Label)
+			return
+		}
+		labels := distinctLabels6(in, active)
+		if len(labels) == 1 {
+			out = appendSegment6(out, start, end, labels[0])
+			return
+		}
+		kept, ok := r.winner(labels, func(a, b uint32) bool {
+			return sizeOf6(in, active, a).less(sizeOf6(in, active, b))
+		})
+		if ok {
+			out = appendSegment6(out, start, end, kept)
+		}
+		c := r.conflict(labels, kept, ok)
+		if pending != nil && !pendEnd.isMax() && pendEnd.addOne() == start && slices.Equal(pending.Labels, c.Labels) && pending.Kept == c.Kept {
+			pendEnd = end
+			return
+		}
+		flush()
+		pending, pendStart, pendEnd = &c, start, end
+	})
+	flush()
+	return out
+}
+
+// winner returns the label that keeps a contested segment; ok is false if
+// the segment is dropped. labels is sorted, i.e. in code order.
+func (r *conflictResolver) winner(labels []uint32, moreSpecific func(a, b uint32) bool) (uint32, bool) {
+	switch r.cfg.conflictPolicy {
+	case ConflictPriority:
+		rank := func(l uint32) int {
+			if i := slices.Index(r.cfg.conflictPriority, r.names[l]); i >= 0 {
+				return i
+			}
+			return len(r.cfg.conflictPriority)
+		}
+		best := labels[0]
+		for _, l := range labels[1:] {
+			if rank(l) < rank(best) {
+				best = l
+			}
+		}
+		return best, true
+	case ConflictMostSpecific:
+		best := labels[0]
+		for _, l := range labels[1:] {
+			if moreSpecific(l, best) {
+				best = l
+			}
+		}
+		return best, true
+	}
+	return 0, false
+}
+
+func (r *conflictResolver) conflict(labels []uint32, kept uint32, ok bool) Conflict {
+	c := Conflict{Category: r.category, Policy: r.cfg.conflictPolicy}
+	for _, l := range labels {
+		c.Labels = append(c.Labels, r.names[l])
+		if int(l) < len(r.files) {
+			c.Files = append(c.Files, r.files[l]...)
+		}
+	}
+	if ok {
+		c.Kept = r.names[kept]
+	}
+	return c
+}
+
+func (r *conflictResolver) report(c Conflict) {
+	if r.count == 0 {
+		r.first = c.String()
+	}
+	r.count++
+	if r.cfg.conflictReport != nil {
+		r.cfg.conflictReport(c)
+	}
+}
+
+// err returns the build error for ConflictFail.
+func (r *conflictResolver) err() error {
+	if r.count == 0 || r.cfg.conflictPolicy != ConflictFail {
+		return nil
+	}
+	problem := r.problem
+	if problem == "" {
+		problem = "overlapping ranges"
+	}
+	if r.count == 1 {
+		return fmt.Errorf("iplist: %s: %s", problem, r.first)
+	}
+	return fmt.Errorf("iplist: %d %s, first: %s", r.count, problem, r.first)
+}
+
+// subdivisionCheck finds the parts of subdivision ranges that lie outside
+// their parent country (the first two letters of the code) and reports them
+// through the resolver of the subdivision category.
+type subdivisionCheck struct {
+	r            *conflictResolver
+	parent       []uint32 // country label by subdivision label; labelNone if absent
+	countries    []string
+	countryFiles [][]string
+}
+
+func newSubdivisionCheck(r *conflictResolver, countries []string, countryFiles [][]string) *subdivisionCheck {
+	c := &subdivisionCheck{r: r, parent: make([]uint32, len(r.names)), countries: countries, countryFiles: countryFiles}
+	for i, code := range r.names {
+		c.parent[i] = labelNone
+		if j, ok := slices.BinarySearch(countries, code[:2]); ok {
+			c.parent[i] = uint32(j)
+		}
+	}
+	return c
+}
+
+// outside reports prefixes of subdivision label sub that belong to country
+// label country, or to no country if it is labelNone.
+func (c *subdivisionCheck) outside(sub, country uint32, prefixes []netip.Prefix) {
+	conf := Conflict{Category: c.r.category, Labels: []string{c.r.names[sub]}, Prefixes: prefixes, Policy: c.r.cfg.conflictPolicy}
+	if int(sub) < len(c.r.files) {
+		conf.Files = append(conf.Files, c.r.files[sub]...)
+	}
+	if country != labelNone {
+		conf.Labels = append(conf.Labels, c.countries[country])
+		if int(country) < len(c.countryFiles) {
+			conf.Files = append(conf.Files, c.countryFiles[country]...)
+		}
+	}
+	c.r.report(conf)
+}
+
+// resolve4 returns sub without the parts outside the parent countries.
+// sub and country must be sorted and disjoint.
+func (c *subdivisionCheck) resolve4(sub, country []entry) []entry {
+	var out []entry
+	for _, e := range sub {
+		i := sort.Search(len(country), func(i int) bool { return country[i].End >= e.Start })
+		for pos := uint64(e.Start); pos <= uint64(e.End); {
+			end, owner := uint64(e.End), labelNone
+			if i < len(country) && uint64(country[i].Start) <= pos {
+				end, owner = min(end, uint64(country[i].End)), country[i].Label
+				i++
+			} else if i < len(country) {
+				end = min(end, uint64(country[i].Start)-1)
+			}
+			if owner != labelNone && owner == c.parent[e.Label] {
+				out = appendSegment(out, uint32(pos), uint32(end), e.Label)
+			} else {
+				prefixes, _ := rangeToCIDRs(uint32(pos), uint32(end))
+				c.outside(e.Label, owner, prefixes)
+			}
+			pos = end + 1
+		}
+	}
+	return out
+}
+
+// resolve6 is the IPv6 counterpart of resolve4.
+func (c *subdivisionCheck) resolve6(sub, country []entry6) []entry6 {
+	var out []entry6
+	for _, e := range sub {
+		i := sort.Search(len(country), func(i int) bool { return !country[i].End.less(e.Start) })
+		for pos := e.Start; ; {
+			end, owner := e.End, labelNone
+			if i < len(country) && !pos.less(country[i].Start) {
+				if country[i].End.less(end) {
+					end = country[i].End
+				}
+				owner = country[i].Label
+				i++
+			} else if i < len(country) && country[i].Start.subOne().less(end) {
+				end = country[i].Start.subOne()
+			}
+			if owner != labelNone && owner == c.parent[e.Label] {
+				out = appendSegment6(out, pos, end, e.Label)
+			} else {
+				prefixes, _ := rangeToCIDRs6(pos, end)
+				c.outside(e.Label, owner, prefixes)
+			}
+			if end == e.End {
+				break
+			}
+			pos = end.addOne()
+		}
+	}
+	return out
+}
+
+func distinctLabels(in []entry, active []int) []uint32 {
+	labels := make([]uint32, 0, len(active))
+	for _, a := range active {
+		labels = append(labels, in[a].Label)
+	}
+	slices.Sort(labels)
+	return slices.Compact(labels)
+}
+
+func distinctLabels6(in []entry6, active []int) []uint32 {
+	labels := make([]uint32, 0, len(active))
+	for _, a := range active {
+		labels = append(labels, in[a].Label)
+	}
+	slices.Sort(labels)
+	return slices.Compact(labels)
+}
+
+// sizeOf returns the size of the smallest active range with label l.
+func sizeOf(in []entry, active []int, l uint32) uint32 {
+	size := ^uint32(0)
+	for _, a := range active {
+		if in[a].Label == l {
+			size = min(size, in[a].End-in[a].Start)
+		}
+	}
+	return size
+}
+
+func sizeOf6(in []entry6, active []int, l uint32) u128 {
+	size := hostMask(128)
+	for _, a := range active {
+		if in[a].Label == l {
+			if s := in[a].End.sub(in[a].Start); s.less(size) {
+				size = s
+			}
+		}
+	}
+	return size
+}
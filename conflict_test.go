@@ -0,0 +1,60 @@
+package iplist
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestConflictPolicy(t *testing.T) {
+	fsys := fstest.MapFS{
+		"country/AA.txt": {Data: []byte("10.0.0.0/8\n2001:db8::/32\n")},
+		"country/BB.txt": {Data: []byte("10.1.0.0/16\n2001:db8:1::/48\n")},
+	}
+	tests := []struct {
+		policy   ConflictPolicy
+		priority []string
+		at       string // owner of 10.1.0.1 and 2001:db8:1::1
+		kept     string
+	}{
+		{ConflictPriority, []string{"AA"}, "AA", "AA"},
+		{ConflictPriority, nil, "AA", "AA"}, // code order
+		{ConflictMostSpecific, nil, "BB", "BB"},
+		{ConflictDropBoth, nil, "", ""},
+	}
+	for _, tt := range tests {
+		var got []Conflict
+		b := NewBuilder(WithConflictPolicy(tt.policy, tt.priority...), WithConflictReport(func(c Conflict) { got = append(got, c) }))
+		if err := b.LoadFS(fsys); err != nil {
+			t.Fatal(err)
+		}
+		db := buildTestDB(t, b)
+		for _, ip := range []string{"10.1.0.1", "2001:db8:1::1"} {
+			if res, _, _ := db.Lookup(ip); res.CountryCode != tt.at {
+				t.Errorf("%v: %s is %q, want %q", tt.policy, ip, res.CountryCode, tt.at)
+			}
+		}
+		if res, _, _ := db.Lookup("10.2.0.1"); res.CountryCode != "AA" {
+			t.Errorf("%v: 10.2.0.1 is %q", tt.policy, res.CountryCode)
+		}
+		if len(got) != 2 {
+			t.Fatalf("%v: %d conflicts reported", tt.policy, len(got))
+		}
+		c := got[0]
+		if c.Category != "country" || !slices.Equal(c.Files, []string{"country/AA.txt", "country/BB.txt"}) ||
+			len(c.Prefixes) != 1 || c.Prefixes[0].String() != "10.1.0.0/16" || c.Kept != tt.kept {
+			t.Errorf("%v: conflict %s", tt.policy, c)
+		}
+	}
+
+	b := NewBuilder()
+	if err := b.LoadFS(fsys); err != nil {
+		t.Fatal(err)
+	}
+	_, err := b.WriteTo(&bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "AA vs BB (country/AA.txt, country/BB.txt) at 10.1.0.0/16") {
+		t.Errorf("fail policy: %v", err)
+	}
+}
@@ -6,7 +6,7 @@ func init() {
 	docsCountryKeys = "ADAEAFAGAIALAMAOAQARASATAUAWAXAZBABBBDBEBFBGBHBIBJBLBMBNBOBQBRBSBTBWBYBZCACDCFCGCHCICKCLCMCNCOCRCUCVCWCXCYCZDEDJDKDMDODZECEEEGERESETFIFJFKFMFOFRGAGBGDGEGFGGGHGIGLGMGNGPGQGRGTGUGWGYHKHNHRHTHUIDIEILIMINIOIQIRISITJEJMJOJPKEKGKHKIKMKNKPKRKWKYKZLALBLCLILKLRLSLTLULVLYMAMCMDMEMFMGMHMKMLMMMNMOMPMQMRMSMTMUMVMWMXMYMZNANCNENFNGNINLNONPNRNUNZOMPAPEPFPGPHPKPLPMPRPSPTPWPYQARERORSRURWSASBSCSDSESGSISKSLSMSNSOSRSSSTSVSXSYSZTCTDTGTHTJTKTLTMTNTOTRTTTVTWTZUAUGUSUYUZVAVCVEVGVIVNVUWFWSXKYEYTZAZMZW"
 	docsCountryVals = "安道尔阿联酋阿富汗安提瓜和巴布达安圭拉阿尔巴尼亚亚美尼亚安哥拉南极洲阿根廷美属萨摩亚奥地利澳大利亚阿鲁巴奥兰阿塞拜疆波斯尼亚和黑塞哥维那巴巴多斯孟加拉国比利时布基纳法索保加利亚巴林布隆迪贝宁圣巴泰勒米百慕大文莱玻利维亚荷兰加勒比区巴西巴哈马不丹博茨瓦纳白俄罗斯伯利兹加拿大刚果（金）中非刚果（布）瑞士科特迪瓦库克群岛智利喀麦隆中国哥伦比亚哥斯达黎加古巴佛得角库拉索圣诞岛塞浦路斯捷克德国吉布提丹麦多米尼克多米尼加阿尔及利亚厄瓜多尔爱沙尼亚埃及厄立特里亚西班牙埃塞俄比亚芬兰斐济福克兰群岛密克罗尼西亚联邦法罗群岛法国加蓬英国格林纳达格鲁吉亚法属圭亚那根西加纳直布罗陀格陵兰冈比亚几内亚瓜德罗普赤道几内亚希腊危地马拉关岛几内亚比绍圭亚那中国香港洪都拉斯克罗地亚海地匈牙利印尼爱尔兰以色列马恩岛印度英属印度洋领地伊拉克伊朗冰岛意大利泽西牙买加约旦日本肯尼亚吉尔吉斯斯坦柬埔寨基里巴斯科摩罗圣基茨和尼维斯朝鲜韩国科威特开曼群岛哈萨克斯坦老挝黎巴嫩圣卢西亚列支敦士登斯里兰卡利比里亚莱索托立陶宛卢森堡拉脱维亚利比亚摩洛哥摩纳哥摩尔多瓦黑山法属圣马丁马达加斯加马绍尔群岛北马其顿马里缅甸蒙古国中国澳门北马里亚纳群岛马提尼克毛里塔尼亚蒙特塞拉特马耳他毛里求斯马尔代夫马拉维墨西哥马来西亚莫桑比克纳米比亚新喀里多尼亚尼日尔诺福克岛尼日利亚尼加拉瓜荷兰挪威尼泊尔瑙鲁纽埃新西兰阿曼巴拿马秘鲁法属波利尼西亚巴布亚新几内亚菲律宾巴基斯坦波兰圣皮埃尔和密克隆波多黎各巴勒斯坦葡萄牙帕劳巴拉圭卡塔尔留尼汪罗马尼亚塞尔维亚俄罗斯卢旺达沙特阿拉伯所罗门群岛塞舌尔苏丹瑞典新加坡斯洛文尼亚斯洛伐克塞拉利昂圣马力诺塞内加尔索马里苏里南南苏丹圣多美和普林西比萨尔瓦多圣马丁叙利亚斯威士兰特克斯和凯科斯群岛乍得多哥泰国塔吉克斯坦托克劳东帝汶土库曼斯坦突尼斯汤加土耳其特立尼达和多巴哥图瓦卢中国台湾坦桑尼亚乌克兰乌干达美国乌拉圭乌兹别克斯坦梵蒂冈圣文森特和格林纳丁斯委内瑞拉英属维尔京群岛美属维尔京群岛越南瓦努阿图瓦利斯和富图纳萨摩亚科索沃也门马约特南非赞比亚津巴布韦"
 	docsCountryOff = []uint32{0, 9, 18, 27, 48, 57, 72, 84, 93, 102, 111, 126, 135, 147, 156, 162, 174, 204, 216, 228, 237, 252, 264, 270, 279, 285, 300, 309, 315, 327, 345, 351, 360, 366, 378, 390, 399, 408, 423, 429, 444, 450, 462, 474, 480, 489, 495, 507, 522, 528, 537, 546, 555, 567, 573, 579, 588, 594, 606, 618, 633, 645, 657, 663, 678, 687, 702, 708, 714, 729, 753, 765, 771, 777, 783, 795, 807, 822, 828, 834, 846, 855, 864, 873, 885, 900, 906, 918, 924, 939, 948, 960, 972, 984, 990, 999, 1005, 1014, 1023, 1032, 1038, 1059, 1068, 1074, 1080, 1089, 1095, 1104, 1110, 1116, 1125, 1143, 1152, 1164, 1173, 1194, 1200, 1206, 1215, 1227, 1242, 1248, 1257, 1269, 1284, 1296, 1308, 1317, 1326, 1335, 1347, 1356, 1365, 1374, 1386, 1392, 1407, 1422, 1437, 1449, 1455, 1461, 1470, 1482, 1503, 1515, 1530, 1545, 1554, 1566, 1578, 1587, 1596, 1608, 1620, 1632, 1650, 1659, 1671, 1683, 1695, 1701, 1707, 1716, 1722, 1728, 1737, 1743, 1752, 1758, 1779, 1800, 1809, 1821, 1827, 1851, 1863, 1875, 1884, 1890, 1899, 1908, 1917, 1929, 1941, 1950, 1959, 1974, 1989, 1998, 2004, 2010, 2019, 2034, 2046, 2058, 2070, 2082, 2091, 2100, 2109, 2133, 2145, 2154, 2163, 2175, 2202, 2208, 2214, 2220, 2235, 2244, 2253, 2268, 2277, 2283, 2292, 2316, 2325, 2337, 2349, 2358, 2367, 2373, 2382, 2400, 2409, 2439, 2451, 2472, 2493, 2499, 2511, 2532, 2541, 2550, 2556, 2565, 2571, 2580, 2592}
-	docsCNCityKeys = "100000110000120000130000130100130200130300130400130500130600130700130800130900131000131100140000140100140200140300140400140500140600140700140800140900141000141100150000150100150200150300150400150500150600150700150800150900152200152500152900210000210100210200210300210400210500210600210700210800210900211000211100211200211300211400220000220100220200220300220400220500220600220700220800222400230000230100230200230300230400230500230600230700230800230900231000231100231200232700310000320000320100320200320300320400320500320600320700320800320900321000321100321200321300330000330100330200330300330400330500330600330700330800330900331000331100340000340100340200340300340400340500340600340700340800341000341100341200341300341500341600341700341800350000350100350200350300350400350500350600350700350800350900360000360100360200360300360400360500360600360700360800360900361000361100370000370100370200370300370400370500370600370700370800370900371000371100371300371400371500371600371700410000410100410200410300410400410500410600410700410800410900411000411100411200411300411400411500411600411700419000420000420100420200420300420500420600420700420800420900421000421100421200421300422800429000430000430100430200430300430400430500430600430700430800430900431000431100431200431300433100440000440100440200440300440400440500440600440700440800440900441200441300441400441500441600441700441800441900442000445100445200445300450000450100450200450300450400450500450600450700450800450900451000451100451200451300451400460000460100460200460400469000500000510000510100510300510400510500510600510700510800510900511000511100511300511400511500511600511700511800511900512000513200513300513400520000520100520200520300520400520500520600522300522600522700530000530100530300530400530500530600530700530800530900532300532500532600532800532900533100533300533400540000540100540200540300540400540500540600542500610000610100610200610300610400610500610600610700610800610900611000620000620100620200620300620400620500620600620700620800620900621000621100621200622900623000630000630100630200632200632300632500632600632700632800640000640100640200640300640400640500650000650100650200650400650500652300652700652800652900653000653100653200654000654200654300659000710000810000820000"
-	docsCNCityVals = "中国北京市天津市河北省石家庄市唐山市秦皇岛市邯郸市邢台市保定市张家口市承德市沧州市廊坊市衡水市山西省太原市大同市阳泉市长治市晋城市朔州市晋中市运城市忻州市临汾市吕梁市内蒙古自治区呼和浩特市包头市乌海市赤峰市通辽市鄂尔多斯市呼伦贝尔市巴彦淖尔市乌兰察布市兴安盟锡林郭勒盟阿拉善盟辽宁省沈阳市大连市鞍山市抚顺市本溪市丹东市锦州市营口市阜新市辽阳市盘锦市铁岭市朝阳市葫芦岛市吉林省长春市吉林市四平市辽源市通化市白山市松原市白城市延边朝鲜族自治州黑龙江省哈尔滨市齐齐哈尔市鸡西市鹤岗市双鸭山市大庆市伊春市佳木斯市七台河市牡丹江市黑河市绥化市大兴安岭地区上海市江苏省南京市无锡市徐州市常州市苏州市南通市连云港市淮安市盐城市扬州市镇江市泰州市宿迁市浙江省杭州市宁波市温州市嘉兴市湖州市绍兴市金华市衢州市舟山市台州市丽水市安徽省合肥市芜湖市蚌埠市淮南市马鞍山市淮北市铜陵市安庆市黄山市滁州市阜阳市宿州市六安市亳州市池州市宣城市福建省福州市厦门市莆田市三明市泉州市漳州市南平市龙岩市宁德市江西省南昌市景德镇市萍乡市九江市新余市鹰潭市赣州市吉安市宜春市抚州市上饶市山东省济南市青岛市淄博市枣庄市东营市烟台市潍坊市济宁市泰安市威海市日照市临沂市德州市聊城市滨州市菏泽市河南省郑州市开封市洛阳市平顶山市安阳市鹤壁市新乡市焦作市濮阳市许昌市漯河市三门峡市南阳市商丘市信阳市周口市驻马店市省直辖县(*)湖北省武汉市黄石市十堰市宜昌市襄阳市鄂州市荆门市孝感市荆州市黄冈市咸宁市随州市恩施土家族苗族自治州省直辖县(*)湖南省长沙市株洲市湘潭市衡阳市邵阳市岳阳市常德市张家界市益阳市郴州市永州市怀化市娄底市湘西土家族苗族自治州广东省广州市韶关市深圳市珠海市汕头市佛山市江门市湛江市茂名市肇庆市惠州市梅州市汕尾市河源市阳江市清远市东莞市中山市潮州市揭阳市云浮市广西壮族自治区南宁市柳州市桂林市梧州市北海市防城港市钦州市贵港市玉林市百色市贺州市河池市来宾市崇左市海南省海口市三亚市儋州市省直辖县(*)重庆市四川省成都市自贡市攀枝花市泸州市德阳市绵阳市广元市遂宁市内江市乐山市南充市眉山市宜宾市广安市达州市雅安市巴中市资阳市阿坝藏族羌族自治州甘孜藏族自治州凉山彝族自治州贵州省贵阳市六盘水市遵义市安顺市毕节市铜仁市黔西南布依族苗族自治州黔东南苗族侗族自治州黔南布依族苗族自治州云南省昆明市曲靖市玉溪市保山市昭通市丽江市普洱市临沧市楚雄彝族自治州红河哈尼族彝族自治州文山壮族苗族自治州西双版纳傣族自治州大理白族自治州德宏傣族景颇族自治州怒江傈僳族自治州迪庆藏族自治州西藏自治区拉萨市日喀则市昌都市林芝市山南市那曲市阿里地区陕西省西安市铜川市宝鸡市咸阳市渭南市延安市汉中市榆林市安康市商洛市甘肃省兰州市嘉峪关市金昌市白银市天水市武威市张掖市平凉市酒泉市庆阳市定西市陇南市临夏回族自治州甘南藏族自治州青海省西宁市海东市海北藏族自治州黄南藏族自治州海南藏族自治州果洛藏族自治州玉树藏族自治州海西蒙古族藏族自治州宁夏回族自治区银川市石嘴山市吴忠市固原市中卫市新疆维吾尔自治区乌鲁木齐市克拉玛依市吐鲁番市哈密市昌吉回族自治州博尔塔拉蒙古自治州巴音郭楞蒙古自治州阿克苏地区克孜勒苏柯尔克孜自治州喀什地区和田地区伊犁哈萨克自治州塔城地区阿勒泰地区省直辖县(*)台湾省香港特别行政区澳门特别行政区"
-	docsCNCityOff = []uint32{0, 6, 15, 24, 33, 45, 54, 66, 75, 84, 93, 105, 114, 123, 132, 141, 150, 159, 168, 177, 186, 195, 204, 213, 222, 231, 240, 249, 267, 282, 291, 300, 309, 318, 333, 348, 363, 378, 387, 402, 414, 423, 432, 441, 450, 459, 468, 477, 486, 495, 504, 513, 522, 531, 540, 552, 561, 570, 579, 588, 597, 606, 615, 624, 633, 657, 669, 681, 696, 705, 714, 726, 735, 744, 756, 768, 780, 789, 798, 816, 825, 834, 843, 852, 861, 870, 879, 888, 900, 909, 918, 927, 936, 945, 954, 963, 972, 981, 990, 999, 1008, 1017, 1026, 1035, 1044, 1053, 1062, 1071, 1080, 1089, 1098, 1107, 1119, 1128, 1137, 1146, 1155, 1164, 1173, 1182, 1191, 1200, 1209, 1218, 1227, 1236, 1245, 1254, 1263, 1272, 1281, 1290, 1299, 1308, 1317, 1326, 1338, 1347, 1356, 1365, 1374, 1383, 1392, 1401, 1410, 1419, 1428, 1437, 1446, 1455, 1464, 1473, 1482, 1491, 1500, 1509, 1518, 1527, 1536, 1545, 1554, 1563, 1572, 1581, 1590, 1599, 1608, 1620, 1629, 1638, 1647, 1656, 1665, 1674, 1683, 1695, 1704, 1713, 1722, 1731, 1743, 1758, 1767, 1776, 1785, 1794, 1803, 1812, 1821, 1830, 1839, 1848, 1857, 1866, 1875, 1905, 1920, 1929, 1938, 1947, 1956, 1965, 1974, 1983, 1992, 2004, 2013, 2022, 2031, 2040, 2049, 2079, 2088, 2097, 2106, 2115, 2124, 2133, 2142, 2151, 2160, 2169, 2178, 2187, 2196, 2205, 2214, 2223, 2232, 2241, 2250, 2259, 2268, 2277, 2298, 2307, 2316, 2325, 2334, 2343, 2355, 2364, 2373, 2382, 2391, 2400, 2409, 2418, 2427, 2436, 2445, 2454, 2463, 2478, 2487, 2496, 2505, 2514, 2526, 2535, 2544, 2553, 2562, 2571, 2580, 2589, 2598, 2607, 2616, 2625, 2634, 2643, 2652, 2661, 2688, 2709, 2730, 2739, 2748, 2760, 2769, 2778, 2787, 2796, 2829, 2859, 2889, 2898, 2907, 2916, 2925, 2934, 2943, 2952, 2961, 2970, 2991, 3021, 3048, 3075, 3096, 3126, 3150, 3171, 3186, 3195, 3207, 3216, 3225, 3234, 3243, 3255, 3264, 3273, 3282, 3291, 3300, 3309, 3318, 3327, 3336, 3345, 3354, 3363, 3372, 3384, 3393, 3402, 3411, 3420, 3429, 3438, 3447, 3456, 3465, 3474, 3495, 3516, 3525, 3534, 3543, 3564, 3585, 3606, 3627, 3648, 3678, 3699, 3708, 3720, 3729, 3738, 3747, 3771, 3786, 3801, 3813, 3822, 3843, 3870, 3897, 3912, 3945, 3957, 3969, 3993, 4005, 4020, 4035, 4044, 4065, 4086}
+	docsCNCityKeys = "100000110000110101110102110105110106110107120000120101120102120103120104120105130000130100130102130104130105130107130108130200130300130400130500130600130700130800130900131000131100140000140100140200140300140400140500140600140700140800140900141000141100150000150100150200150300150400150500150600150700150800150900152200152500152900210000210100210200210300210400210500210600210700210800210900211000211100211200211300211400220000220100220200220300220400220500220600220700220800222400230000230100230200230300230400230500230600230700230800230900231000231100231200232700310000320000320100320200320300320400320500320600320700320800320900321000321100321200321300330000330100330200330300330400330500330600330700330800330900331000331100340000340100340200340300340400340500340600340700340800341000341100341200341300341500341600341700341800350000350100350200350300350400350500350600350700350800350900360000360100360200360300360400360500360600360700360800360900361000361100370000370100370200370300370400370500370600370700370800370900371000371100371300371400371500371600371700410000410100410200410300410400410500410600410700410800410900411000411100411200411300411400411500411600411700419000419001420000420100420200420300420500420600420700420800420900421000421100421200421300422800429000429004429005429006429021430000430100430200430300430400430500430600430700430800430900431000431100431200431300433100440000440100440200440300440400440500440600440700440800440900441200441300441400441500441600441700441800441900442000445100445200445300450000450100450200450300450400450500450600450700450800450900451000451100451200451300451400460000460100460200460400469000469001469002469005469006469007469021469022469023469024469025469026469027469028469029469030500000510000510100510300510400510500510600510700510800510900511000511100511300511400511500511600511700511800511900512000513200513300513400520000520100520200520300520400520500520600522300522600522700530000530100530300530400530500530600530700530800530900532300532500532600532800532900533100533300533400540000540100540200540300540400540500540600542500610000610100610200610300610400610500610600610700610800610900611000620000620100620200620300620400620500620600620700620800620900621000621100621200622900623000630000630100630200632200632300632500632600632700632800640000640100640200640300640400640500650000650100650200650400650500652300652700652800652900653000653100653200654000654200654300659000659001659002659003659004659006710000810000820000"
+	docsCNCityVals = "中国北京市东城区西城区朝阳区丰台区石景山区天津市和平区河东区河西区南开区河北区河北省石家庄市长安区桥西区新华区井陉矿区裕华区唐山市秦皇岛市邯郸市邢台市保定市张家口市承德市沧州市廊坊市衡水市山西省太原市大同市阳泉市长治市晋城市朔州市晋中市运城市忻州市临汾市吕梁市内蒙古自治区呼和浩特市包头市乌海市赤峰市通辽市鄂尔多斯市呼伦贝尔市巴彦淖尔市乌兰察布市兴安盟锡林郭勒盟阿拉善盟辽宁省沈阳市大连市鞍山市抚顺市本溪市丹东市锦州市营口市阜新市辽阳市盘锦市铁岭市朝阳市葫芦岛市吉林省长春市吉林市四平市辽源市通化市白山市松原市白城市延边朝鲜族自治州黑龙江省哈尔滨市齐齐哈尔市鸡西市鹤岗市双鸭山市大庆市伊春市佳木斯市七台河市牡丹江市黑河市绥化市大兴安岭地区上海市江苏省南京市无锡市徐州市常州市苏州市南通市连云港市淮安市盐城市扬州市镇江市泰州市宿迁市浙江省杭州市宁波市温州市嘉兴市湖州市绍兴市金华市衢州市舟山市台州市丽水市安徽省合肥市芜湖市蚌埠市淮南市马鞍山市淮北市铜陵市安庆市黄山市滁州市阜阳市宿州市六安市亳州市池州市宣城市福建省福州市厦门市莆田市三明市泉州市漳州市南平市龙岩市宁德市江西省南昌市景德镇市萍乡市九江市新余市鹰潭市赣州市吉安市宜春市抚州市上饶市山东省济南市青岛市淄博市枣庄市东营市烟台市潍坊市济宁市泰安市威海市日照市临沂市德州市聊城市滨州市菏泽市河南省郑州市开封市洛阳市平顶山市安阳市鹤壁市新乡市焦作市濮阳市许昌市漯河市三门峡市南阳市商丘市信阳市周口市驻马店市省直辖县(*)济源湖北省武汉市黄石市十堰市宜昌市襄阳市鄂州市荆门市孝感市荆州市黄冈市咸宁市随州市恩施土家族苗族自治州省直辖县(*)仙桃潜江天门神农架林区湖南省长沙市株洲市湘潭市衡阳市邵阳市岳阳市常德市张家界市益阳市郴州市永州市怀化市娄底市湘西土家族苗族自治州广东省广州市韶关市深圳市珠海市汕头市佛山市江门市湛江市茂名市肇庆市惠州市梅州市汕尾市河源市阳江市清远市东莞市中山市潮州市揭阳市云浮市广西壮族自治区南宁市柳州市桂林市梧州市北海市防城港市钦州市贵港市玉林市百色市贺州市河池市来宾市崇左市海南省海口市三亚市儋州市省直辖县(*)五指山琼海文昌万宁东方定安县屯昌县澄迈县临高县白沙黎族自治县昌江黎族自治县乐东黎族自治县陵水黎族自治县保亭黎族苗族自治县琼中黎族苗族自治县重庆市四川省成都市自贡市攀枝花市泸州市德阳市绵阳市广元市遂宁市内江市乐山市南充市眉山市宜宾市广安市达州市雅安市巴中市资阳市阿坝藏族羌族自治州甘孜藏族自治州凉山彝族自治州贵州省贵阳市六盘水市遵义市安顺市毕节市铜仁市黔西南布依族苗族自治州黔东南苗族侗族自治州黔南布依族苗族自治州云南省昆明市曲靖市玉溪市保山市昭通市丽江市普洱市临沧市楚雄彝族自治州红河哈尼族彝族自治州文山壮族苗族自治州西双版纳傣族自治州大理白族自治州德宏傣族景颇族自治州怒江傈僳族自治州迪庆藏族自治州西藏自治区拉萨市日喀则市昌都市林芝市山南市那曲市阿里地区陕西省西安市铜川市宝鸡市咸阳市渭南市延安市汉中市榆林市安康市商洛市甘肃省兰州市嘉峪关市金昌市白银市天水市武威市张掖市平凉市酒泉市庆阳市定西市陇南市临夏回族自治州甘南藏族自治州青海省西宁市海东市海北藏族自治州黄南藏族自治州海南藏族自治州果洛藏族自治州玉树藏族自治州海西蒙古族藏族自治州宁夏回族自治区银川市石嘴山市吴忠市固原市中卫市新疆维吾尔自治区乌鲁木齐市克拉玛依市吐鲁番市哈密市昌吉回族自治州博尔塔拉蒙古自治州巴音郭楞蒙古自治州阿克苏地区克孜勒苏柯尔克孜自治州喀什地区和田地区伊犁哈萨克自治州塔城地区阿勒泰地区省直辖县(*)石河子阿拉尔图木舒克五家渠铁门关台湾省香港特别行政区澳门特别行政区"
+	docsCNCityOff = []uint32{0, 6, 15, 24, 33, 42, 51, 63, 72, 81, 90, 99, 108, 117, 126, 138, 147, 156, 165, 177, 186, 195, 207, 216, 225, 234, 246, 255, 264, 273, 282, 291, 300, 309, 318, 327, 336, 345, 354, 363, 372, 381, 390, 408, 423, 432, 441, 450, 459, 474, 489, 504, 519, 528, 543, 555, 564, 573, 582, 591, 600, 609, 618, 627, 636, 645, 654, 663, 672, 681, 693, 702, 711, 720, 729, 738, 747, 756, 765, 774, 798, 810, 822, 837, 846, 855, 867, 876, 885, 897, 909, 921, 930, 939, 957, 966, 975, 984, 993, 1002, 1011, 1020, 1029, 1041, 1050, 1059, 1068, 1077, 1086, 1095, 1104, 1113, 1122, 1131, 1140, 1149, 1158, 1167, 1176, 1185, 1194, 1203, 1212, 1221, 1230, 1239, 1248, 1260, 1269, 1278, 1287, 1296, 1305, 1314, 1323, 1332, 1341, 1350, 1359, 1368, 1377, 1386, 1395, 1404, 1413, 1422, 1431, 1440, 1449, 1458, 1467, 1479, 1488, 1497, 1506, 1515, 1524, 1533, 1542, 1551, 1560, 1569, 1578, 1587, 1596, 1605, 1614, 1623, 1632, 1641, 1650, 1659, 1668, 1677, 1686, 1695, 1704, 1713, 1722, 1731, 1740, 1749, 1761, 1770, 1779, 1788, 1797, 1806, 1815, 1824, 1836, 1845, 1854, 1863, 1872, 1884, 1899, 1905, 1914, 1923, 1932, 1941, 1950, 1959, 1968, 1977, 1986, 1995, 2004, 2013, 2022, 2052, 2067, 2073, 2079, 2085, 2100, 2109, 2118, 2127, 2136, 2145, 2154, 2163, 2172, 2184, 2193, 2202, 2211, 2220, 2229, 2259, 2268, 2277, 2286, 2295, 2304, 2313, 2322, 2331, 2340, 2349, 2358, 2367, 2376, 2385, 2394, 2403, 2412, 2421, 2430, 2439, 2448, 2457, 2478, 2487, 2496, 2505, 2514, 2523, 2535, 2544, 2553, 2562, 2571, 2580, 2589, 2598, 2607, 2616, 2625, 2634, 2643, 2658, 2667, 2673, 2679, 2685, 2691, 2700, 2709, 2718, 2727, 2748, 2769, 2790, 2811, 2838, 2865, 2874, 2883, 2892, 2901, 2913, 2922, 2931, 2940, 2949, 2958, 2967, 2976, 2985, 2994, 3003, 3012, 3021, 3030, 3039, 3048, 3075, 3096, 3117, 3126, 3135, 3147, 3156, 3165, 3174, 3183, 3216, 3246, 3276, 3285, 3294, 3303, 3312, 3321, 3330, 3339, 3348, 3357, 3378, 3408, 3435, 3462, 3483, 3513, 3537, 3558, 3573, 3582, 3594, 3603, 3612, 3621, 3630, 3642, 3651, 3660, 3669, 3678, 3687, 3696, 3705, 3714, 3723, 3732, 3741, 3750, 3759, 3771, 3780, 3789, 3798, 3807, 3816, 3825, 3834, 3843, 3852, 3861, 3882, 3903, 3912, 3921, 3930, 3951, 3972, 3993, 4014, 4035, 4065, 4086, 4095, 4107, 4116, 4125, 4134, 4158, 4173, 4188, 4200, 4209, 4230, 4257, 4284, 4299, 4332, 4344, 4356, 4380, 4392, 4407, 4422, 4431, 4440, 4452, 4461, 4470, 4479, 4500, 4521}
 }
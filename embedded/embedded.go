@@ -0,0 +1,44 @@
+// Package embedded provides the iplist database built from this module's
+// data/ directory, compiled into the binary.
+//
+// It follows golang.org/x/net/publicsuffix: single-binary deployments get a
+// ready database without shipping a separate data file. The snapshot is as
+// fresh as the module version the binary was built with.
+package embedded
+
+import (
+	_ "embed"
+	"sync"
+
+	"github.com/dnsoa/iplist"
+)
+
+// go:embed cannot reach the repository root, so the database built there is
+// copied here; go generate ./... rebuilds the root file first. Unless
+// SOURCE_DATE_EPOCH is set, the build time is the commit time of data/, so
+// regenerating unchanged data stamps the same build time.
+//
+//go:generate go run ../internal/cmd/copy-db -from ../iplist.db -to ./iplist.db
+
+//go:embed iplist.db
+var data []byte
+
+var (
+	once sync.Once
+	db   *iplist.DB
+)
+
+// DB returns the embedded database, opening it on first use.
+// The returned DB is shared and must not be closed.
+//
+// DB panics if the embedded file is corrupt, which indicates a broken build.
+func DB() *iplist.DB {
+	once.Do(func() {
+		var err error
+		db, err = iplist.OpenBytes(data)
+		if err != nil {
+			panic("iplist/embedded: " + err.Error())
+		}
+	})
+	return db
+}
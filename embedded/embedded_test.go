@@ -0,0 +1,65 @@
+package embedded
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/dnsoa/iplist"
+)
+
+// TestOpenVariants opens the committed file through every constructor and
+// checks that they agree with the embedded DB.
+func TestOpenVariants(t *testing.T) {
+	raw, err := os.ReadFile("iplist.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(raw, data) {
+		t.Fatal("embedded data differs from iplist.db")
+	}
+	root, err := os.ReadFile("../iplist.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(root, raw) {
+		t.Fatal("embedded/iplist.db is not a copy of ../iplist.db; run go generate ./...")
+	}
+	open := map[string]func() (*iplist.DB, error){
+		"Open":      func() (*iplist.DB, error) { return iplist.Open("iplist.db") },
+		"OpenBytes": func() (*iplist.DB, error) { return iplist.OpenBytes(raw) },
+		"OpenReader": func() (*iplist.DB, error) {
+			return iplist.OpenReader(bytes.NewReader(raw), int64(len(raw)))
+		},
+		"OpenFS": func() (*iplist.DB, error) { return iplist.OpenFS(os.DirFS("."), "iplist.db") },
+	}
+
+	want := DB()
+	wantMD, err := want.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ips := []string{"1.2.4.8", "8.8.8.8", "223.5.5.5", "2400:3200::1", "192.0.2.1"}
+	for name, fn := range open {
+		db, err := fn()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		md, err := db.Metadata()
+		if err != nil || !reflect.DeepEqual(md, wantMD) {
+			t.Errorf("%s: Metadata = %+v, %v; want %+v", name, md, err, wantMD)
+		}
+		for _, ip := range ips {
+			got, gotOK, gotErr := db.Lookup(ip)
+			exp, expOK, expErr := want.Lookup(ip)
+			if gotOK != expOK || gotErr != expErr || !reflect.DeepEqual(got, exp) {
+				t.Errorf("%s: Lookup(%s) = %+v, %v, %v; want %+v, %v, %v", name, ip, got, gotOK, gotErr, exp, expOK, expErr)
+			}
+		}
+		db.Close()
+	}
+	if res, ok, _ := want.Lookup("1.2.4.8"); !ok || res.CountryCode != "CN" {
+		t.Errorf("embedded: 1.2.4.8 = %+v, %v", res, ok)
+	}
+}
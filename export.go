@@ -39,13 +39,46 @@ func (db *DB) ExportCountryTSV(w io.Writer) error {
 	return bw.Flush()
 }
 
+// ExportSubdivisionTSV writes the full SubdivisionID -> (code,name) mapping table.
+// The name column is empty until the data provides subdivision names.
+//
+// Output header:
+//   subdivision_id\tsubdivision_code\tsubdivision_name
+func (db *DB) ExportSubdivisionTSV(w io.Writer) error {
+	if db == nil || db.v4 == nil {
+		return ErrInvalidDB
+	}
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString("subdivision_id\tsubdivision_code\tsubdivision_name\n"); err != nil {
+		return err
+	}
+	var line []byte
+	for i := range db.v4.subdivLabels {
+		id := uint32(i)
+		code, name := db.v4.subdivLabel(id)
+		if code == "" {
+			continue
+		}
+		line = strconv.AppendUint(line[:0], uint64(id), 10)
+		line = append(line, '\t')
+		line = append(line, code...)
+		line = append(line, '\t')
+		line = append(line, name...)
+		line = append(line, '\n')
+		if _, err := bw.Write(line); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
 // ExportCNProvinceTSV writes the full CNProvinceID -> (code,name) mapping table.
 //
 // CNProvinceID values are indices into the CN label table.
 // Output header:
 //   cn_province_id\tcn_province_code\tcn_province_name
 func (db *DB) ExportCNProvinceTSV(w io.Writer) error {
-	return db.exportCNTSV(w, true)
+	return db.exportCNTSV(w, "cn_province")
 }
 
 // ExportCNCityTSV writes the full CNCityID -> (code,name) mapping table.
@@ -54,18 +87,25 @@ func (db *DB) ExportCNProvinceTSV(w io.Writer) error {
 // Output header:
 //   cn_city_id\tcn_city_code\tcn_city_name
 func (db *DB) ExportCNCityTSV(w io.Writer) error {
-	return db.exportCNTSV(w, false)
+	return db.exportCNTSV(w, "cn_city")
 }
 
-func (db *DB) exportCNTSV(w io.Writer, province bool) error {
+// ExportCNDistrictTSV writes the full CNDistrictID -> (code,name) mapping table.
+//
+// CNDistrictID values are indices into the CN label table.
+// Output header:
+//   cn_district_id\tcn_district_code\tcn_district_name
+func (db *DB) ExportCNDistrictTSV(w io.Writer) error {
+	return db.exportCNTSV(w, "cn_district")
+}
+
+// exportCNTSV writes the CN labels of one level: cn_province, cn_city or cn_district.
+func (db *DB) exportCNTSV(w io.Writer, level string) error {
 	if db == nil || db.v4 == nil {
 		return ErrInvalidDB
 	}
 	bw := bufio.NewWriter(w)
-	head := "cn_city_id\tcn_city_code\tcn_city_name\n"
-	if province {
-		head = "cn_province_id\tcn_province_code\tcn_province_name\n"
-	}
+	head := level + "_id\t" + level + "_code\t" + level + "_name\n"
 	if _, err := bw.WriteString(head); err != nil {
 		return err
 	}
@@ -77,16 +117,8 @@ func (db *DB) exportCNTSV(w io.Writer, province bool) error {
 		if code == "" {
 			continue
 		}
-		isProv := strings.HasSuffix(code, "0000")
-		isCity := strings.HasSuffix(code, "00") && !isProv
-		if province {
-			if !isProv {
-				continue
-			}
-		} else {
-			if !isCity {
-				continue
-			}
+		if cnLevel(code) != level {
+			continue
 		}
 
 		line = strconv.AppendUint(line[:0], uint64(id), 10)
@@ -135,3 +167,15 @@ func (db *DB) ExportProviderTSV(w io.Writer) error {
 	}
 	return bw.Flush()
 }
+
+// cnLevel classifies a 6-digit CN admin code by its trailing zeros.
+func cnLevel(code string) string {
+	switch {
+	case strings.HasSuffix(code, "0000"):
+		return "cn_province"
+	case strings.HasSuffix(code, "00"):
+		return "cn_city"
+	default:
+		return "cn_district"
+	}
+}
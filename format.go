@@ -27,3 +27,28 @@ type providerLabel struct {
 	Name uint32
 	Kind uint32
 }
+
+// entry6 is the IPv6 counterpart of entry.
+// Start/End are inclusive IPv6 integer addresses.
+type entry6 struct {
+	Start u128
+	End   u128
+	Label uint32
+}
+
+// Section header of v2 files, which are only read; Build writes v3 (see
+// section.go). It is 160 bytes:
+//
+//	0..24    label tables: (off, count) for country, cn, provider
+//	24..88   IPv4 tables: (startsOff, endsOff, labelsOff, count) x4
+//	88..152  IPv6 tables: (startsOff, endsOff, labelsOff, count) x4
+//	152..160 reserved
+//
+// IPv6 starts/ends are u128 values (Hi then Lo, each a little-endian u64).
+// v2 files written before IPv6 support have zeros from 88 on, which reads
+// as a file without IPv6 data.
+const (
+	sectionHeaderSize = 160
+	secV4TablesOff    = 24
+	secV6TablesOff    = 88
+)
@@ -1,11 +1,14 @@
-// Package iplist provides a compact IPv4 IP-to-label database and lookup APIs.
+// Package iplist provides a compact IPv4/IPv6 IP-to-label database and lookup APIs.
 package iplist
 
-// Regenerate the prebuilt database file from the repository data directory.
+// Regenerate the derived files from the repository sources.
 //
 // This follows the same pattern as golang.org/x/net/publicsuffix: keep the
 // human-editable sources (data/, docs/) in the repo and use `go generate`
-// to produce the derived binary artifact.
+// to produce the derived artifacts. The prebuilt database is ./iplist.db;
+// package embedded copies it to embedded/iplist.db. The build uses the
+// most-specific conflict policy so that overlaps or stray subdivision ranges
+// in the upstream data are dropped and reported instead of failing it.
 //
-//go:generate go run ./internal/cmd/gen-names -country ./docs/country.md -cncity ./docs/cncity.md -out ./docs_names_gen.go
-//go:generate go run ./cmd/iplist build -data ./data -out ./iplist.db
+//go:generate go run ./internal/cmd/gen-names -country ./docs/country.md -cncity ./docs/cncity.md -cac ./src/plugins/cac/data.js -out ./docs_names_gen.go
+//go:generate go run ./cmd/iplist build -data ./data -out ./iplist.db -conflict most-specific
@@ -0,0 +1,31 @@
+// Command copy-db copies the database built at the repository root into
+// package embedded, which go:embed cannot reach from there. It is run by
+// go generate after the root database has been rebuilt.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	var (
+		from = flag.String("from", "../iplist.db", "database to copy")
+		to   = flag.String("to", "iplist.db", "destination")
+	)
+	flag.Parse()
+
+	data, err := os.ReadFile(*from)
+	if err != nil {
+		die(err)
+	}
+	if err := os.WriteFile(*to, data, 0o644); err != nil {
+		die(err)
+	}
+}
+
+func die(err error) {
+	_, _ = fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
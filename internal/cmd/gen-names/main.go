@@ -12,17 +12,20 @@ import (
 	"regexp"
 	"sort"
 	"strconv"
+	"strings"
 )
 
 var (
 	reCountryLine = regexp.MustCompile(`\|([^|]+)\|https?://[^\s|]*/data/country/([A-Z]{2})\.txt\|?`)
 	reCNLine      = regexp.MustCompile(`\|([^|]+)\|https?://[^\s|]*/data/cncity/(\d{6})\.txt\|?`)
+	reCACLine     = regexp.MustCompile(`'([^']+)':\s*(\d{6})`)
 )
 
 func main() {
 	var (
 		countryPath = flag.String("country", "docs/country.md", "path to docs/country.md")
 		cncityPath  = flag.String("cncity", "docs/cncity.md", "path to docs/cncity.md")
+		cacPath     = flag.String("cac", "", "path to src/plugins/cac/data.js (adds district names)")
 		outPath     = flag.String("out", "docs_names_gen.go", "output Go file (package iplist)")
 	)
 	flag.Parse()
@@ -35,6 +38,19 @@ func main() {
 	if err != nil {
 		die(err)
 	}
+	if *cacPath != "" {
+		// docs/cncity.md only lists provinces and cities; take the remaining
+		// (county/district) names from the admin-code data.
+		cacNames, err := parseNames(*cacPath, reCACLine)
+		if err != nil {
+			die(err)
+		}
+		for code, name := range cacNames {
+			if _, ok := cnNames[code]; !ok && !strings.HasSuffix(code, "00") {
+				cnNames[code] = name
+			}
+		}
+	}
 
 	outAbs, err := filepath.Abs(*outPath)
 	if err != nil {
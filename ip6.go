@@ -0,0 +1,94 @@
+package iplist
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"net/netip"
+)
+
+// u128 is an IPv6 address as an unsigned 128-bit integer.
+// Hi holds the first 8 bytes of the address in network order.
+type u128 struct {
+	Hi uint64
+	Lo uint64
+}
+
+func u128FromAddr(addr netip.Addr) u128 {
+	b := addr.As16()
+	return u128{Hi: binary.BigEndian.Uint64(b[0:8]), Lo: binary.BigEndian.Uint64(b[8:16])}
+}
+
+func (x u128) addr() netip.Addr {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[0:8], x.Hi)
+	binary.BigEndian.PutUint64(b[8:16], x.Lo)
+	return netip.AddrFrom16(b)
+}
+
+func (x u128) less(y u128) bool {
+	return x.Hi < y.Hi || (x.Hi == y.Hi && x.Lo < y.Lo)
+}
+
+func (x u128) isZero() bool {
+	return x.Hi == 0 && x.Lo == 0
+}
+
+func (x u128) isMax() bool {
+	return x.Hi == ^uint64(0) && x.Lo == ^uint64(0)
+}
+
+func (x u128) addOne() u128 {
+	lo := x.Lo + 1
+	hi := x.Hi
+	if lo == 0 {
+		hi++
+	}
+	return u128{Hi: hi, Lo: lo}
+}
+
+func (x u128) subOne() u128 {
+	lo := x.Lo - 1
+	hi := x.Hi
+	if x.Lo == 0 {
+		hi--
+	}
+	return u128{Hi: hi, Lo: lo}
+}
+
+func (x u128) sub(y u128) u128 {
+	lo := x.Lo - y.Lo
+	hi := x.Hi - y.Hi
+	if x.Lo < y.Lo {
+		hi--
+	}
+	return u128{Hi: hi, Lo: lo}
+}
+
+// trailingZeros returns the number of trailing zero bits (128 for zero).
+func (x u128) trailingZeros() int {
+	if x.Lo != 0 {
+		return bits.TrailingZeros64(x.Lo)
+	}
+	if x.Hi != 0 {
+		return 64 + bits.TrailingZeros64(x.Hi)
+	}
+	return 128
+}
+
+// hostMask returns a value with the low n bits set (0 <= n <= 128).
+func hostMask(n int) u128 {
+	switch {
+	case n <= 0:
+		return u128{}
+	case n < 64:
+		return u128{Lo: (uint64(1) << n) - 1}
+	case n < 128:
+		return u128{Hi: (uint64(1) << (n - 64)) - 1, Lo: ^uint64(0)}
+	default:
+		return u128{Hi: ^uint64(0), Lo: ^uint64(0)}
+	}
+}
+
+func (x u128) or(y u128) u128 {
+	return u128{Hi: x.Hi | y.Hi, Lo: x.Lo | y.Lo}
+}
@@ -8,20 +8,30 @@ import (
 
 // DB is an opened IP list database.
 //
-// The current implementation supports IPv4 only.
+// Both IPv4 and IPv6 addresses are supported. IPv4-mapped IPv6 addresses
+// (::ffff:a.b.c.d) are looked up in the IPv4 tables.
 //
-// Use Open to load a built database file.
+// Use Open to load a built database file, or OpenBytes/OpenReader/OpenFS to
+// load one from memory. The embedded subpackage provides the database
+// committed with this module.
 // Use the cmd/iplist build command to generate a database file from a data/ directory.
 type DB struct {
-	raw  *os.File
-	data []byte
-	v4   *v4DB
+	raw    *os.File
+	data   []byte
+	mapped bool // data is an mmap of raw
+	v4     *v4DB
+
+	// heapStrings reports that results do not point into a mapping, either
+	// because data is not one or because of WithHeapStrings.
+	heapStrings bool
 }
 
 // Result is the lookup result for a single IP.
 // Fields may be empty when the category has no match.
 //
-// City is based on CN admin code data (province/city level).
+// City is based on CN admin code data (province/city/district level). When a
+// city or district matches, the levels above it are filled as well (see
+// WithoutCNProvinceFill).
 // Provider covers both ISP and cloud vendors.
 type Result struct {
 	IP netip.Addr
@@ -29,15 +39,43 @@ type Result struct {
 	CountryCode string
 	CountryName string
 
+	// SubdivisionCode is the ISO 3166-2 code, e.g. GB-ABE, TH-11.
+	// SubdivisionName is its name, empty if the data has none.
+	SubdivisionCode string
+	SubdivisionName string
+
 	CNProvinceCode string
 	CNProvinceName string
 
 	CNCityCode string
 	CNCityName string
 
+	// CNDistrictCode is the county/district (区县) level. The database
+	// shipped with this module has no district data yet, so it is only
+	// set for databases built from data with district files.
+	CNDistrictCode string
+	CNDistrictName string
+
 	ProviderKey  string // e.g. aliyun, chinatelecom
 	ProviderName string
 	ProviderKind ProviderKind
+
+	// Special lists the special sets containing the IP, e.g. SpecialChina.
+	// It is independent of CountryCode. The slice is shared; do not modify it.
+	Special []string
+
+	// Categories lists the labels of the custom categories containing the
+	// IP, in DB.Categories order. The slice is reused by the *Into lookups.
+	Categories []CategoryLabel
+
+	// Ranges holds the range of the entry each dimension matched.
+	// Network is the largest prefix containing IP on which every dimension
+	// (including all providers, special sets and categories) gives the same
+	// answer, so the result can be cached for the whole prefix; it is set
+	// whether or not the IP matched. Both are only filled when the DB was
+	// opened WithNetworks.
+	Ranges  Ranges
+	Network netip.Prefix
 }
 
 // ResultIDs is a low-level lookup result that only contains label IDs.
@@ -48,40 +86,84 @@ type Result struct {
 type ResultIDs struct {
 	IP netip.Addr
 
-	CountryID    uint32
-	CNProvinceID uint32
-	CNCityID     uint32
+	CountryID     uint32
+	SubdivisionID uint32
+	CNProvinceID  uint32
+	CNCityID      uint32
+	CNDistrictID  uint32
 
 	ProviderID   uint32
 	ProviderKind ProviderKind
+	// ProviderIDs lists every provider whose ranges contain IP, most
+	// specific first (ProviderIDs[0] == ProviderID). It points into the
+	// database: do not modify it or use it after Close, unless the DB was
+	// opened with WithHeapStrings.
+	ProviderIDs []uint32
+
+	// SpecialMask has bit 1<<i set for special set i (see DB.SpecialSets
+	// and DB.SpecialMask). Zero when the IP is in no special set.
+	SpecialMask uint32
+
+	// CategoryIDs has one label ID per custom category (see DB.Categories
+	// and DB.CategoryLabels), IDNone where the IP has no label. The slice is
+	// reused by the *Into lookups.
+	CategoryIDs []uint32
+
+	// Ranges and Network are as in Result.
+	Ranges  Ranges
+	Network netip.Prefix
 }
 
 const IDNone uint32 = ^uint32(0)
 
+// ProviderKind is a set of provider kinds; a provider may have several,
+// e.g. ProviderKindCloud|ProviderKindCDN. See kind.go.
 type ProviderKind uint8
 
 const (
-	ProviderKindUnknown ProviderKind = 0
-	ProviderKindISP     ProviderKind = 1
-	ProviderKindCloud   ProviderKind = 2
+	ProviderKindUnknown    ProviderKind = 0
+	ProviderKindISP        ProviderKind = 1
+	ProviderKindCloud      ProviderKind = 2
+	ProviderKindCDN        ProviderKind = 4
+	ProviderKindEducation  ProviderKind = 8 // education and research networks
+	ProviderKindMobile     ProviderKind = 16
+	ProviderKindHosting    ProviderKind = 32
+	ProviderKindEnterprise ProviderKind = 64
 )
 
 var (
-	ErrInvalidDB      = errors.New("iplist: invalid db")
-	ErrUnsupportedIP  = errors.New("iplist: unsupported ip (ipv4 only)")
-	ErrInvalidIP      = errors.New("iplist: invalid ip")
-	ErrNilResult      = errors.New("iplist: nil result")
-	ErrUnknownVendor  = errors.New("iplist: unknown provider")
-	ErrUnknownCountry = errors.New("iplist: unknown country")
-	ErrUnknownCity    = errors.New("iplist: unknown cn city")
+	ErrInvalidDB       = errors.New("iplist: invalid db")
+	ErrUnsupportedIP   = errors.New("iplist: unsupported ip")
+	ErrInvalidIP       = errors.New("iplist: invalid ip")
+	ErrNilResult       = errors.New("iplist: nil result")
+	ErrUnknownVendor   = errors.New("iplist: unknown provider")
+	ErrUnknownCountry  = errors.New("iplist: unknown country")
+	ErrUnknownCity     = errors.New("iplist: unknown cn city")
+	ErrUnknownSpecial  = errors.New("iplist: unknown special set")
+	ErrUnknownCategory = errors.New("iplist: unknown category")
 )
 
 // Open opens an existing database file built by cmd/iplist build.
-func Open(path string) (*DB, error) {
-	return open(path)
+//
+// The file checksum is verified unless WithoutChecksum is given. A file that
+// is shorter or longer than its header records, or that does not match its
+// checksum, yields ErrChecksumMismatch. Files written before checksums were
+// added (format version 2) are only checked for bounds.
+//
+// Verification reads the whole file, so Open costs O(file size) and faults
+// every page in. WithoutChecksum is the supported fast path for processes
+// that open a trusted file often (for example one verified once at deploy
+// time): Open then only reads the label tables and builds a few small maps,
+// which costs some tens of allocations regardless of the number of ranges.
+func Open(path string, opts ...OpenOption) (*DB, error) {
+	return open(path, newOpenConfig(opts))
 }
 
 // Close releases underlying resources.
+//
+// Strings in results obtained from a mapped DB point into the mapping and must
+// not be used after Close unless the DB was opened with WithHeapStrings.
+// Use AtomicDB or Reloader to replace a DB that is still being read.
 func (db *DB) Close() error {
 	if db == nil {
 		return nil
@@ -131,7 +213,7 @@ func (db *DB) LookupAddr(addr netip.Addr) (Result, bool, error) {
 	if db == nil || db.v4 == nil {
 		return Result{}, false, ErrInvalidDB
 	}
-	if !addr.Is4() {
+	if !addr.IsValid() {
 		return Result{}, false, ErrUnsupportedIP
 	}
 	return db.v4.lookup(addr)
@@ -155,7 +237,7 @@ func (db *DB) LookupAddrIDsInto(addr netip.Addr, dst *ResultIDs) (bool, error) {
 	if dst == nil {
 		return false, ErrNilResult
 	}
-	if !addr.Is4() {
+	if !addr.IsValid() {
 		clearResultIDs(dst)
 		return false, ErrUnsupportedIP
 	}
@@ -200,12 +282,10 @@ func (db *DB) LookupAddrProviderID(addr netip.Addr) (providerID uint32, kind Pro
 	if db == nil || db.v4 == nil {
 		return IDNone, ProviderKindUnknown, false, ErrInvalidDB
 	}
-	if !addr.Is4() {
+	if !addr.IsValid() {
 		return IDNone, ProviderKindUnknown, false, ErrUnsupportedIP
 	}
-	ip4 := addr.As4()
-	ip := uint32(ip4[0])<<24 | uint32(ip4[1])<<16 | uint32(ip4[2])<<8 | uint32(ip4[3])
-	providerID, kind, ok = db.v4.lookupProviderIDU32(ip)
+	providerID, kind, ok = db.v4.lookupProviderID(addr)
 	return providerID, kind, ok, nil
 }
 
@@ -229,7 +309,7 @@ func (db *DB) LookupAddrInto(addr netip.Addr, dst *Result) (bool, error) {
 	if dst == nil {
 		return false, ErrNilResult
 	}
-	if !addr.Is4() {
+	if !addr.IsValid() {
 		clearResult(dst)
 		return false, ErrUnsupportedIP
 	}
@@ -248,22 +328,37 @@ func clearResult(dst *Result) {
 	dst.IP = netip.Addr{}
 	dst.CountryCode = ""
 	dst.CountryName = ""
+	dst.SubdivisionCode = ""
+	dst.SubdivisionName = ""
 	dst.CNProvinceCode = ""
 	dst.CNProvinceName = ""
 	dst.CNCityCode = ""
 	dst.CNCityName = ""
+	dst.CNDistrictCode = ""
+	dst.CNDistrictName = ""
 	dst.ProviderKey = ""
 	dst.ProviderName = ""
 	dst.ProviderKind = ProviderKindUnknown
+	dst.Special = nil
+	dst.Categories = dst.Categories[:0]
+	dst.Ranges = Ranges{}
+	dst.Network = netip.Prefix{}
 }
 
 func clearResultIDs(dst *ResultIDs) {
 	dst.IP = netip.Addr{}
 	dst.CountryID = IDNone
+	dst.SubdivisionID = IDNone
 	dst.CNProvinceID = IDNone
 	dst.CNCityID = IDNone
+	dst.CNDistrictID = IDNone
 	dst.ProviderID = IDNone
 	dst.ProviderKind = ProviderKindUnknown
+	dst.ProviderIDs = nil
+	dst.SpecialMask = 0
+	dst.CategoryIDs = dst.CategoryIDs[:0]
+	dst.Ranges = Ranges{}
+	dst.Network = netip.Prefix{}
 }
 
 // Decode helpers (cold path)
@@ -279,6 +374,18 @@ func (db *DB) CountryByID(id uint32) (code, name string, ok bool) {
 	return code, name, code != ""
 }
 
+// SubdivisionByID decodes a ResultIDs.SubdivisionID.
+func (db *DB) SubdivisionByID(id uint32) (code, name string, ok bool) {
+	if db == nil || db.v4 == nil {
+		return "", "", false
+	}
+	if id == IDNone {
+		return "", "", false
+	}
+	code, name = db.v4.subdivLabel(id)
+	return code, name, code != ""
+}
+
 func (db *DB) CNByID(id uint32) (code, name string, ok bool) {
 	if db == nil || db.v4 == nil {
 		return "", "", false
@@ -302,7 +409,7 @@ func (db *DB) ProviderByID(id uint32) (key, name string, kind ProviderKind, ok b
 }
 
 // CloudIPs returns all CIDRs for a given cloud vendor (e.g. "aliyun").
-// Output lines are normalized CIDR strings.
+// Output lines are normalized CIDR strings, IPv4 first, then IPv6.
 func (db *DB) CloudIPs(vendor string) ([]string, error) {
 	if db == nil || db.v4 == nil {
 		return nil, ErrInvalidDB
@@ -310,8 +417,8 @@ func (db *DB) CloudIPs(vendor string) ([]string, error) {
 	return db.v4.providerCIDRs(vendor, ProviderKindCloud)
 }
 
-// ProviderIPs returns all CIDRs for a provider (ISP or cloud).
-// It also returns the resolved kind.
+// ProviderIPs returns all CIDRs for a provider (ISP or cloud),
+// IPv4 first, then IPv6. It also returns the resolved kind.
 func (db *DB) ProviderIPs(provider string) ([]string, ProviderKind, error) {
 	if db == nil || db.v4 == nil {
 		return nil, ProviderKindUnknown, ErrInvalidDB
@@ -0,0 +1,143 @@
+package iplist
+
+import "net/netip"
+
+// ipSet is a set of addresses as sorted, disjoint and non-adjacent ranges.
+// Entry labels are unused.
+type ipSet struct {
+	v4 []entry
+	v6 []entry6
+}
+
+func newIPSet(r4 []entry, r6 []entry6) ipSet {
+	for i := range r4 {
+		r4[i].Label = 0
+	}
+	for i := range r6 {
+		r6[i].Label = 0
+	}
+	return ipSet{v4: mergeByLabel(r4), v6: mergeByLabel6(r6)}
+}
+
+func (s ipSet) union(o ipSet) ipSet {
+	return newIPSet(append(append([]entry(nil), s.v4...), o.v4...), append(append([]entry6(nil), s.v6...), o.v6...))
+}
+
+func (s ipSet) intersect(o ipSet) ipSet {
+	var out ipSet
+	for i, j := 0, 0; i < len(s.v4) && j < len(o.v4); {
+		a, b := s.v4[i], o.v4[j]
+		if lo, hi := max(a.Start, b.Start), min(a.End, b.End); lo <= hi {
+			out.v4 = append(out.v4, entry{Start: lo, End: hi})
+		}
+		if a.End < b.End {
+			i++
+		} else {
+			j++
+		}
+	}
+	for i, j := 0, 0; i < len(s.v6) && j < len(o.v6); {
+		a, b := s.v6[i], o.v6[j]
+		lo, hi := a.Start, a.End
+		if lo.less(b.Start) {
+			lo = b.Start
+		}
+		if b.End.less(hi) {
+			hi = b.End
+		}
+		if !hi.less(lo) {
+			out.v6 = append(out.v6, entry6{Start: lo, End: hi})
+		}
+		if a.End.less(b.End) {
+			i++
+		} else {
+			j++
+		}
+	}
+	return out
+}
+
+func (s ipSet) minus(o ipSet) ipSet {
+	var out ipSet
+	j := 0
+	for _, r := range s.v4 {
+		for j < len(o.v4) && o.v4[j].End < r.Start {
+			j++
+		}
+		cur, done := r.Start, false
+		for k := j; k < len(o.v4) && o.v4[k].Start <= r.End; k++ {
+			if o.v4[k].Start > cur {
+				out.v4 = append(out.v4, entry{Start: cur, End: o.v4[k].Start - 1})
+			}
+			if o.v4[k].End >= r.End {
+				done = true
+				break
+			}
+			cur = o.v4[k].End + 1
+		}
+		if !done {
+			out.v4 = append(out.v4, entry{Start: cur, End: r.End})
+		}
+	}
+	j = 0
+	for _, r := range s.v6 {
+		for j < len(o.v6) && o.v6[j].End.less(r.Start) {
+			j++
+		}
+		cur, done := r.Start, false
+		for k := j; k < len(o.v6) && !r.End.less(o.v6[k].Start); k++ {
+			if cur.less(o.v6[k].Start) {
+				out.v6 = append(out.v6, entry6{Start: cur, End: o.v6[k].Start.subOne()})
+			}
+			if !o.v6[k].End.less(r.End) {
+				done = true
+				break
+			}
+			cur = o.v6[k].End.addOne()
+		}
+		if !done {
+			out.v6 = append(out.v6, entry6{Start: cur, End: r.End})
+		}
+	}
+	return out
+}
+
+// prefixes calls fn with the minimal CIDRs covering s, IPv4 first.
+func (s ipSet) prefixes(fn func(netip.Prefix) error) error {
+	for _, e := range s.v4 {
+		ps, err := rangeToCIDRs(e.Start, e.End)
+		if err != nil {
+			return err
+		}
+		for _, p := range ps {
+			if err := fn(p); err != nil {
+				return err
+			}
+		}
+	}
+	for _, e := range s.v6 {
+		ps, err := rangeToCIDRs6(e.Start, e.End)
+		if err != nil {
+			return err
+		}
+		for _, p := range ps {
+			if err := fn(p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// cidrs returns s as normalized CIDR strings, IPv4 first.
+func (s ipSet) cidrs() ([]string, error) {
+	out := []string{}
+	err := s.prefixes(func(p netip.Prefix) error {
+		out = append(out, p.String())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
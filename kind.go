@@ -0,0 +1,93 @@
+package iplist
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var providerKindNames = [...]struct {
+	kind ProviderKind
+	name string
+}{
+	{ProviderKindISP, "isp"},
+	{ProviderKindCloud, "cloud"},
+	{ProviderKindCDN, "cdn"},
+	{ProviderKindEducation, "education"},
+	{ProviderKindMobile, "mobile"},
+	{ProviderKindHosting, "hosting"},
+	{ProviderKindEnterprise, "enterprise"},
+}
+
+// Has reports whether k includes every kind in other.
+func (k ProviderKind) Has(other ProviderKind) bool {
+	return other != ProviderKindUnknown && k&other == other
+}
+
+// String returns the kind names joined by commas, e.g. "cloud,cdn", or
+// "unknown" for ProviderKindUnknown. Bits without a name (from a newer
+// builder) are rendered in hex, e.g. "cloud,0x80".
+func (k ProviderKind) String() string {
+	if k == ProviderKindUnknown {
+		return "unknown"
+	}
+	var names []string
+	for _, n := range providerKindNames {
+		if k&n.kind != 0 {
+			names = append(names, n.name)
+			k &^= n.kind
+		}
+	}
+	if k != 0 {
+		names = append(names, fmt.Sprintf("0x%x", uint8(k)))
+	}
+	return strings.Join(names, ",")
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (k ProviderKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It accepts the output
+// of String: comma-separated kind names, "unknown" or the empty string.
+func (k *ProviderKind) UnmarshalText(text []byte) error {
+	v, err := ParseProviderKind(string(text))
+	if err != nil {
+		return err
+	}
+	*k = v
+	return nil
+}
+
+// ParseProviderKind parses comma-separated kind names such as "cloud,cdn".
+// It also accepts the hex form String uses for unnamed bits, e.g. "0x80".
+func ParseProviderKind(s string) (ProviderKind, error) {
+	var k ProviderKind
+	for _, f := range strings.Split(s, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if f == "" || f == "unknown" {
+			continue
+		}
+		if hex, ok := strings.CutPrefix(f, "0x"); ok {
+			v, err := strconv.ParseUint(hex, 16, 8)
+			if err != nil {
+				return ProviderKindUnknown, fmt.Errorf("iplist: unknown provider kind %q", f)
+			}
+			k |= ProviderKind(v)
+			continue
+		}
+		found := false
+		for _, n := range providerKindNames {
+			if n.name == f {
+				k |= n.kind
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ProviderKindUnknown, fmt.Errorf("iplist: unknown provider kind %q", f)
+		}
+	}
+	return k, nil
+}
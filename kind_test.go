@@ -0,0 +1,56 @@
+package iplist
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestProviderKindText(t *testing.T) {
+	tests := []struct {
+		kind ProviderKind
+		text string
+	}{
+		{ProviderKindUnknown, "unknown"},
+		{ProviderKindISP, "isp"},
+		{ProviderKindCloud | ProviderKindCDN, "cloud,cdn"},
+		{ProviderKindISP | ProviderKindMobile, "isp,mobile"},
+		{ProviderKindEducation, "education"},
+		{ProviderKindCloud | 0x80, "cloud,0x80"},
+		{0x80, "0x80"},
+	}
+	for _, tt := range tests {
+		if s := tt.kind.String(); s != tt.text {
+			t.Errorf("%d.String() = %q, want %q", tt.kind, s, tt.text)
+		}
+		var k ProviderKind
+		if err := k.UnmarshalText([]byte(tt.text)); err != nil || k != tt.kind {
+			t.Errorf("UnmarshalText(%q) = %d, %v", tt.text, k, err)
+		}
+	}
+	if k, err := ParseProviderKind(" CDN , cloud"); err != nil || k != ProviderKindCloud|ProviderKindCDN {
+		t.Errorf("ParseProviderKind = %d, %v", k, err)
+	}
+	for _, s := range []string{"telco", "0x", "0x100", "0xzz"} {
+		if _, err := ParseProviderKind(s); err == nil {
+			t.Errorf("ParseProviderKind(%q) accepted", s)
+		}
+	}
+
+	// Every value survives a MarshalText/UnmarshalText round trip.
+	for i := 0; i < 256; i++ {
+		want := ProviderKind(i)
+		text, _ := want.MarshalText()
+		var got ProviderKind
+		if err := got.UnmarshalText(text); err != nil || got != want {
+			t.Errorf("round trip of %d via %q = %d, %v", want, text, got, err)
+		}
+	}
+
+	b, _ := json.Marshal(map[string]ProviderKind{"k": ProviderKindHosting | ProviderKindEnterprise})
+	if string(b) != `{"k":"hosting,enterprise"}` {
+		t.Errorf("json: %s", b)
+	}
+	if !(ProviderKindCloud | ProviderKindCDN).Has(ProviderKindCDN) || ProviderKindCloud.Has(ProviderKindUnknown) {
+		t.Error("Has")
+	}
+}
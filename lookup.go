@@ -15,9 +15,12 @@ func (v *v4DB) lookup(addr netip.Addr) (Result, bool, error) {
 }
 
 func (v *v4DB) lookupInto(addr netip.Addr, dst *Result) (bool, error) {
-	ip4 := addr.As4()
-	ip := uint32(ip4[0])<<24 | uint32(ip4[1])<<16 | uint32(ip4[2])<<8 | uint32(ip4[3])
-	return v.lookupIntoU32(ip, dst)
+	if addr.Is4() || addr.Is4In6() {
+		ip4 := addr.As4()
+		ip := uint32(ip4[0])<<24 | uint32(ip4[1])<<16 | uint32(ip4[2])<<8 | uint32(ip4[3])
+		return v.lookupIntoU32(ip, dst)
+	}
+	return v.lookupIntoU128(u128FromAddr(addr), dst)
 }
 
 func (v *v4DB) lookupIntoU32(ip uint32, dst *Result) (bool, error) {
@@ -31,16 +34,16 @@ func (v *v4DB) lookupIntoU32(ip uint32, dst *Result) (bool, error) {
 		matched = true
 	}
 
-	// CN city data (prefer city-level match).
-	if label, ok := v.cnCity.lookup(ip); ok {
-		code, name := v.cnLabel(label)
-		dst.CNCityCode = code
-		dst.CNCityName = name
+	if label, ok := v.subdiv.lookup(ip); ok {
+		dst.SubdivisionCode, dst.SubdivisionName = v.subdivLabel(label)
 		matched = true
-	} else if label, ok := v.cnProv.lookup(ip); ok {
-		code, name := v.cnLabel(label)
-		dst.CNProvinceCode = code
-		dst.CNProvinceName = name
+	}
+
+	district, city, prov := v.cnLookup4(ip)
+	if prov != IDNone || city != IDNone || district != IDNone {
+		dst.CNProvinceCode, dst.CNProvinceName = v.cnLabel(prov)
+		dst.CNCityCode, dst.CNCityName = v.cnLabel(city)
+		dst.CNDistrictCode, dst.CNDistrictName = v.cnLabel(district)
 		matched = true
 	}
 
@@ -52,13 +55,81 @@ func (v *v4DB) lookupIntoU32(ip uint32, dst *Result) (bool, error) {
 		matched = true
 	}
 
+	if mask, ok := v.special.lookup(ip); ok {
+		dst.Special = v.specialNames(mask)
+		matched = true
+	}
+
+	if len(v.categories) > 0 {
+		if dst.Categories = v.lookupCategories4(ip, dst.Categories[:0]); len(dst.Categories) > 0 {
+			matched = true
+		}
+	}
+
+	if v.networks {
+		dst.Network = v.ranges4(ip, district, city, prov, &dst.Ranges)
+	}
+
+	return matched, nil
+}
+
+func (v *v4DB) lookupIntoU128(ip u128, dst *Result) (bool, error) {
+
+	matched := false
+
+	if label, ok := v.country6.lookup(ip); ok {
+		code, name := v.countryLabel(label)
+		dst.CountryCode = code
+		dst.CountryName = name
+		matched = true
+	}
+
+	if label, ok := v.subdiv6.lookup(ip); ok {
+		dst.SubdivisionCode, dst.SubdivisionName = v.subdivLabel(label)
+		matched = true
+	}
+
+	district, city, prov := v.cnLookup6(ip)
+	if prov != IDNone || city != IDNone || district != IDNone {
+		dst.CNProvinceCode, dst.CNProvinceName = v.cnLabel(prov)
+		dst.CNCityCode, dst.CNCityName = v.cnLabel(city)
+		dst.CNDistrictCode, dst.CNDistrictName = v.cnLabel(district)
+		matched = true
+	}
+
+	if label, ok := v.provider6.lookup(ip); ok {
+		key, name, kind := v.providerLabel(label)
+		dst.ProviderKey = key
+		dst.ProviderName = name
+		dst.ProviderKind = kind
+		matched = true
+	}
+
+	if mask, ok := v.special6.lookup(ip); ok {
+		dst.Special = v.specialNames(mask)
+		matched = true
+	}
+
+	if len(v.categories) > 0 {
+		if dst.Categories = v.lookupCategories6(ip, dst.Categories[:0]); len(dst.Categories) > 0 {
+			matched = true
+		}
+	}
+
+	if v.networks {
+		dst.Network = v.ranges6(ip, district, city, prov, &dst.Ranges)
+	}
+
 	return matched, nil
 }
 
 func (v *v4DB) lookupIDsInto(addr netip.Addr, dst *ResultIDs) (bool, error) {
-	ip4 := addr.As4()
-	ip := uint32(ip4[0])<<24 | uint32(ip4[1])<<16 | uint32(ip4[2])<<8 | uint32(ip4[3])
-	return v.lookupIDsIntoU32(ip, dst)
+	if addr.Is4() || addr.Is4In6() {
+		ip4 := addr.As4()
+		ip := uint32(ip4[0])<<24 | uint32(ip4[1])<<16 | uint32(ip4[2])<<8 | uint32(ip4[3])
+		return v.lookupIDsIntoU32(ip, dst)
+	}
+	return v.lookupIDsIntoU128(u128FromAddr(addr), dst)
 }
 
 func (v *v4DB) lookupIDsIntoU32(ip uint32, dst *ResultIDs) (bool, error) {
@@ -70,11 +141,16 @@ func (v *v4DB) lookupIDsIntoU32(ip uint32, dst *ResultIDs) (bool, error) {
 		matched = true
 	}
 
-	if label, ok := v.cnCity.lookup(ip); ok {
-		dst.CNCityID = label
+	if label, ok := v.subdiv.lookup(ip); ok {
+		dst.SubdivisionID = label
 		matched = true
-	} else if label, ok := v.cnProv.lookup(ip); ok {
-		dst.CNProvinceID = label
+	}
+
+	district, city, prov := v.cnLookup4(ip)
+	if prov != IDNone || city != IDNone || district != IDNone {
+		dst.CNProvinceID = prov
+		dst.CNCityID = city
+		dst.CNDistrictID = district
 		matched = true
 	}
 
@@ -82,13 +158,159 @@ func (v *v4DB) lookupIDsIntoU32(ip uint32, dst *ResultIDs) (bool, error) {
 		dst.ProviderID = label
 		if label < uint32(len(v.providerLabels)) {
 			dst.ProviderKind = ProviderKind(v.providerLabels[label].Kind)
+			dst.ProviderIDs = v.providerIDs4(ip, label)
 		}
 		matched = true
 	}
 
+	if mask, ok := v.special.lookup(ip); ok {
+		dst.SpecialMask = mask
+		matched = true
+	}
+
+	if len(v.categories) > 0 {
+		var ok bool
+		if dst.CategoryIDs, ok = v.lookupCategoryIDs4(ip, dst.CategoryIDs[:0]); ok {
+			matched = true
+		}
+	}
+
+	if v.networks {
+		dst.Network = v.ranges4(ip, district, city, prov, &dst.Ranges)
+	}
+
 	return matched, nil
 }
 
+func (v *v4DB) lookupIDsIntoU128(ip u128, dst *ResultIDs) (bool, error) {
+
+	matched := false
+
+	if label, ok := v.country6.lookup(ip); ok {
+		dst.CountryID = label
+		matched = true
+	}
+
+	if label, ok := v.subdiv6.lookup(ip); ok {
+		dst.SubdivisionID = label
+		matched = true
+	}
+
+	district, city, prov := v.cnLookup6(ip)
+	if prov != IDNone || city != IDNone || district != IDNone {
+		dst.CNProvinceID = prov
+		dst.CNCityID = city
+		dst.CNDistrictID = district
+		matched = true
+	}
+
+	if label, ok := v.provider6.lookup(ip); ok {
+		dst.ProviderID = label
+		if label < uint32(len(v.providerLabels)) {
+			dst.ProviderKind = ProviderKind(v.providerLabels[label].Kind)
+			dst.ProviderIDs = v.providerIDs6(ip, label)
+		}
+		matched = true
+	}
+
+	if mask, ok := v.special6.lookup(ip); ok {
+		dst.SpecialMask = mask
+		matched = true
+	}
+
+	if len(v.categories) > 0 {
+		var ok bool
+		if dst.CategoryIDs, ok = v.lookupCategoryIDs6(ip, dst.CategoryIDs[:0]); ok {
+			matched = true
+		}
+	}
+
+	if v.networks {
+		dst.Network = v.ranges6(ip, district, city, prov, &dst.Ranges)
+	}
+
+	return matched, nil
+}
+
+// cnLookup4 returns the CN district, city and province labels of ip, IDNone
+// where absent. Unless WithoutCNProvinceFill was given, the levels above the
+// most specific match are filled from its admin-code prefix (440305 → 440300
+// → 440000), falling back to the city/province tables; otherwise only the
+// most specific level is returned.
+func (v *v4DB) cnLookup4(ip uint32) (district, city, prov uint32) {
+	district, city, prov = IDNone, IDNone, IDNone
+	if l, ok := v.cnDist.lookup(ip); ok {
+		if district = l; !v.fillCNProvince {
+			return
+		}
+		city = v.cnUp(v.cnCityOf, l)
+	}
+	if city == IDNone {
+		if l, ok := v.cnCity.lookup(ip); ok {
+			if city = l; !v.fillCNProvince {
+				return
+			}
+		}
+	}
+	if city != IDNone {
+		prov = v.cnUp(v.cnProvOf, city)
+	} else if district != IDNone {
+		prov = v.cnUp(v.cnProvOf, district)
+	}
+	if prov == IDNone {
+		if l, ok := v.cnProv.lookup(ip); ok {
+			prov = l
+		}
+	}
+	return
+}
+
+// cnLookup6 is the IPv6 counterpart of cnLookup4.
+func (v *v4DB) cnLookup6(ip u128) (district, city, prov uint32) {
+	district, city, prov = IDNone, IDNone, IDNone
+	if l, ok := v.cnDist6.lookup(ip); ok {
+		if district = l; !v.fillCNProvince {
+			return
+		}
+		city = v.cnUp(v.cnCityOf, l)
+	}
+	if city == IDNone {
+		if l, ok := v.cnCity6.lookup(ip); ok {
+			if city = l; !v.fillCNProvince {
+				return
+			}
+		}
+	}
+	if city != IDNone {
+		prov = v.cnUp(v.cnProvOf, city)
+	} else if district != IDNone {
+		prov = v.cnUp(v.cnProvOf, district)
+	}
+	if prov == IDNone {
+		if l, ok := v.cnProv6.lookup(ip); ok {
+			prov = l
+		}
+	}
+	return
+}
+
+func (v *v4DB) lookupProviderID(addr netip.Addr) (providerID uint32, kind ProviderKind, ok bool) {
+	if addr.Is4() || addr.Is4In6() {
+		ip4 := addr.As4()
+		ip := uint32(ip4[0])<<24 | uint32(ip4[1])<<16 | uint32(ip4[2])<<8 | uint32(ip4[3])
+		return v.lookupProviderIDU32(ip)
+	}
+	label, ok := v.provider6.lookup(u128FromAddr(addr))
+	if !ok {
+		return IDNone, ProviderKindUnknown, false
+	}
+	providerID = label
+	if label < uint32(len(v.providerLabels)) {
+		kind = ProviderKind(v.providerLabels[label].Kind)
+	}
+	return providerID, kind, true
+}
+
 func (v *v4DB) lookupProviderIDU32(ip uint32) (providerID uint32, kind ProviderKind, ok bool) {
 	label, ok := v.provider.lookup(ip)
 	if !ok {
@@ -101,7 +323,7 @@ func (v *v4DB) lookupProviderIDU32(ip uint32) (providerID uint32, kind ProviderK
 	return providerID, kind, true
 }
 
-func (t v4Table) lookup(ip uint32) (uint32, bool) {
+func (t *v4Table) lookup(ip uint32) (uint32, bool) {
 	if len(t.starts) == 0 {
 		return 0, false
 	}
@@ -111,10 +333,10 @@ func (t v4Table) lookup(ip uint32) (uint32, bool) {
 
 	lo := 0
 	hi := len(t.starts)
-	if t.bucketLo16 != nil && t.bucketHi16 != nil {
-		p := int(ip >> 16)
-		lo = int(t.bucketLo16[p])
-		hi = int(t.bucketHi16[p])
+	if t.bucketLo != nil && t.bucketHi != nil {
+		p := int(ip >> t.bucketShift)
+		lo = int(t.bucketLo[p])
+		hi = int(t.bucketHi[p])
 		if lo < 0 {
 			lo = 0
 		}
@@ -177,3 +399,24 @@ func (t v4Table) lookup(ip uint32) (uint32, bool) {
 	}
 	return label, true
 }
+
+func (t *v6Table) lookup(ip u128) (uint32, bool) {
+	// First index with start > ip.
+	i, j := 0, len(t.starts)
+	for i < j {
+		h := (i + j) >> 1
+		if ip.less(t.starts[h]) {
+			j = h
+		} else {
+			i = h + 1
+		}
+	}
+	idx := i - 1
+	if idx < 0 {
+		return 0, false
+	}
+	if t.ends[idx].less(ip) {
+		return 0, false
+	}
+	return t.labels[idx], true
+}
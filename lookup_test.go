@@ -0,0 +1,107 @@
+package iplist
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/netip"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCNProvinceFill(t *testing.T) {
+	b := NewBuilder()
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.0.0.0/16"), "440000")
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.0.0.0/24"), "440300")
+	// No province range covers this city range.
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.1.0.0/24"), "440100")
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, fill := range []bool{true, false} {
+		var opts []OpenOption
+		if !fill {
+			opts = append(opts, WithoutCNProvinceFill())
+		}
+		db, err := OpenBytes(buf.Bytes(), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ip := range []string{"10.0.0.1", "10.1.0.1"} {
+			res, _, _ := db.Lookup(ip)
+			want := ""
+			if fill {
+				want = "440000"
+			}
+			if res.CNProvinceCode != want || res.CNCityCode == "" {
+				t.Errorf("fill=%v %s: province %q city %q", fill, ip, res.CNProvinceCode, res.CNCityCode)
+			}
+			ids, _, _ := db.LookupIDs(ip)
+			if (ids.CNProvinceID != IDNone) != fill {
+				t.Errorf("fill=%v %s: province id %d", fill, ip, ids.CNProvinceID)
+			}
+		}
+		if res, _, _ := db.Lookup("10.0.1.1"); res.CNProvinceCode != "440000" || res.CNCityCode != "" {
+			t.Errorf("fill=%v: province-only %+v", fill, res)
+		}
+		db.Close()
+	}
+}
+
+func TestCNDistrict(t *testing.T) {
+	b := NewBuilder()
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.0.0.0/16"), "440000")
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.0.0.0/20"), "440300")
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.0.0.0/24"), "440305")
+	// District without a city label: the province still resolves.
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.1.64.0/24"), "469001")
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.1.0.0/16"), "460000")
+	db := buildTestDB(t, b)
+
+	tests := []struct{ ip, prov, city, dist string }{
+		{"10.0.0.1", "440000", "440300", "440305"},
+		{"10.0.1.1", "440000", "440300", ""},
+		{"10.1.64.1", "460000", "", "469001"},
+	}
+	for _, tt := range tests {
+		res, _, _ := db.Lookup(tt.ip)
+		if res.CNProvinceCode != tt.prov || res.CNCityCode != tt.city || res.CNDistrictCode != tt.dist {
+			t.Errorf("%s: got %q %q %q", tt.ip, res.CNProvinceCode, res.CNCityCode, res.CNDistrictCode)
+		}
+	}
+	ids, _, _ := db.LookupIDs("10.0.0.1")
+	if code, _, _ := db.CNByID(ids.CNDistrictID); code != "440305" {
+		t.Errorf("district id %d decodes to %q", ids.CNDistrictID, code)
+	}
+}
+
+// TestTableReceivers guards the lookup hot path: the tables are large structs
+// and a value receiver copies one on every probe, which costs more than the
+// search itself (see BenchmarkTableLookupOnly_8_160_0_3).
+func TestTableReceivers(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range f.Decls {
+			fn, ok := d.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil {
+				continue
+			}
+			if id, ok := fn.Recv.List[0].Type.(*ast.Ident); ok && (id.Name == "v4Table" || id.Name == "v6Table") {
+				t.Errorf("%s.%s has a value receiver", id.Name, fn.Name.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package iplist
+
+import (
+	"io"
+	"io/fs"
+	"unsafe"
+)
+
+// OpenBytes opens a database held in memory, e.g. a //go:embed []byte.
+//
+// The DB reads b in place when it is suitably aligned, so b must not be
+// modified while the DB is in use. Close is a no-op for memory-backed
+// databases apart from dropping the reference to b.
+func OpenBytes(b []byte, opts ...OpenOption) (*DB, error) {
+	// Tables are sliced in place and need 8-byte alignment. Big-endian hosts
+	// byte-swap the tables on open, which must not touch the caller's memory.
+	if !nativeLittleEndian || (len(b) > 0 && uintptr(unsafe.Pointer(&b[0]))%8 != 0) {
+		buf := alignedBytes(len(b))
+		copy(buf, b)
+		b = buf
+	}
+	return openData(b, nil, newOpenConfig(opts))
+}
+
+// OpenReader reads a whole database of the given size from r into memory
+// and opens it.
+func OpenReader(r io.ReaderAt, size int64, opts ...OpenOption) (*DB, error) {
+	if size < headerSize || int64(int(size)) != size {
+		return nil, ErrInvalidDB
+	}
+	buf := alignedBytes(int(size))
+	if _, err := io.ReadFull(io.NewSectionReader(r, 0, size), buf); err != nil {
+		return nil, err
+	}
+	return openData(buf, nil, newOpenConfig(opts))
+}
+
+// OpenFS opens the database file name from fsys (for example an embed.FS)
+// by reading it into memory.
+func OpenFS(fsys fs.FS, name string, opts ...OpenOption) (*DB, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if ra, ok := f.(io.ReaderAt); ok {
+		return OpenReader(ra, st.Size(), opts...)
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return OpenBytes(b, opts...)
+}
+
+// alignedBytes returns a zeroed n-byte slice whose backing array is 8-byte aligned.
+func alignedBytes(n int) []byte {
+	if n == 0 {
+		return nil
+	}
+	words := make([]uint64, (n+7)/8)
+	return unsafe.Slice((*byte)(unsafe.Pointer(&words[0])), n)
+}
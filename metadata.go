@@ -0,0 +1,77 @@
+package iplist
+
+import (
+	"encoding/json"
+	"runtime/debug"
+	"time"
+)
+
+// Metadata describes how a database file was built.
+//
+// Files written before the metadata section existed only report BuildTime.
+type Metadata struct {
+	BuildTime time.Time `json:"-"`
+
+	// Sources names the upstream data sets, e.g. OpenIPDB, IPinfo, bgp.tools.
+	Sources []string `json:"sources,omitempty"`
+	// DataRevision is the revision (usually a git commit) of the data directory.
+	DataRevision string `json:"data_revision,omitempty"`
+	// BuilderVersion identifies the iplist version that wrote the file.
+	BuilderVersion string `json:"builder_version,omitempty"`
+
+	// Counts holds per-category range/label counts keyed by category name:
+	// country, subdivision, cn_province, cn_city, cn_district, provider and
+	// special (whose Labels is the number of special sets), plus one key per
+	// custom category (see DB.Categories).
+	Counts map[string]CategoryCount `json:"counts,omitempty"`
+
+	// Extra holds free-form key/values passed to Build via WithMetadata.
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+// CategoryCount is the size of one category table.
+type CategoryCount struct {
+	Ranges4 int `json:"ranges4"`
+	Ranges6 int `json:"ranges6"`
+	Labels  int `json:"labels"`
+}
+
+const modulePath = "github.com/dnsoa/iplist"
+
+// builderVersion reports the version of this module as seen by the running binary.
+func builderVersion() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "iplist (unknown)"
+	}
+	if bi.Main.Path == modulePath {
+		return "iplist " + bi.Main.Version
+	}
+	for _, dep := range bi.Deps {
+		if dep.Path == modulePath {
+			return "iplist " + dep.Version
+		}
+	}
+	return "iplist (devel)"
+}
+
+// Metadata returns the build metadata stored in the database file.
+func (db *DB) Metadata() (Metadata, error) {
+	if db == nil || db.v4 == nil {
+		return Metadata{}, ErrInvalidDB
+	}
+	var md Metadata
+	if len(db.v4.metadata) > 0 {
+		if err := json.Unmarshal(db.v4.metadata, &md); err != nil {
+			return Metadata{}, ErrInvalidDB
+		}
+	}
+	if db.v4.buildTime != 0 {
+		md.BuildTime = time.Unix(db.v4.buildTime, 0).UTC()
+	}
+	return md, nil
+}
+
+func encodeMetadata(md *Metadata) ([]byte, error) {
+	return json.Marshal(md)
+}
@@ -0,0 +1,66 @@
+package iplist
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMetadata(t *testing.T) {
+	buildTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	b := NewBuilder(
+		WithBuildTime(buildTime),
+		WithSources("OpenIPDB", "bgp.tools"),
+		WithDataRevision("abc123"),
+		WithBuilderVersion("iplist test"),
+		WithMetadata("channel", "nightly"),
+	)
+	_ = b.AddCountry(netip.MustParsePrefix("10.0.0.0/8"), "CN")
+	_ = b.AddCountry(netip.MustParsePrefix("2001:db8::/32"), "CN")
+	_ = b.AddCountry(netip.MustParsePrefix("192.0.2.0/24"), "US")
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.1.0.0/16"), "440000")
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.1.1.0/24"), "440100")
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.1.1.0/25"), "440106")
+	_ = b.AddProvider(netip.MustParsePrefix("10.0.0.0/8"), "chinatelecom", "", ProviderKindISP)
+	_ = b.AddSpecial(netip.MustParsePrefix("10.2.0.0/16"), "anycast")
+	_ = b.AddCategory(netip.MustParsePrefix("10.3.0.0/16"), "office", "beijing")
+	db := buildTestDB(t, b)
+
+	md, err := db.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !md.BuildTime.Equal(buildTime) {
+		t.Errorf("BuildTime = %v, want %v", md.BuildTime, buildTime)
+	}
+	if !slices.Equal(md.Sources, []string{"OpenIPDB", "bgp.tools"}) || md.DataRevision != "abc123" || md.BuilderVersion != "iplist test" {
+		t.Errorf("Metadata = %+v", md)
+	}
+	if len(md.Extra) != 1 || md.Extra["channel"] != "nightly" {
+		t.Errorf("Extra = %v", md.Extra)
+	}
+	want := map[string]CategoryCount{
+		"country":     {Ranges4: 2, Ranges6: 1, Labels: 2},
+		"subdivision": {},
+		"cn_province": {Ranges4: 1, Labels: 1},
+		"cn_city":     {Ranges4: 1, Labels: 1},
+		"cn_district": {Ranges4: 1, Labels: 1},
+		"provider":    {Ranges4: 1, Labels: 1},
+		"special":     {Ranges4: 1, Labels: 1},
+		"office":      {Ranges4: 1, Labels: 1},
+	}
+	if len(md.Counts) != len(want) {
+		t.Errorf("Counts has %d keys, want %d: %v", len(md.Counts), len(want), md.Counts)
+	}
+	for k, w := range want {
+		if got := md.Counts[k]; got != w {
+			t.Errorf("Counts[%s] = %+v, want %+v", k, got, w)
+		}
+	}
+
+	var nilDB *DB
+	if _, err := nilDB.Metadata(); err != ErrInvalidDB {
+		t.Errorf("nil DB: %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package iplist
+
+import "syscall"
+
+// mapPopulate prefaults the mapping in the mmap call itself.
+const mapPopulate = syscall.MAP_POPULATE
+
+func adviseMapping(b []byte, a Advice) error {
+	advice := syscall.MADV_NORMAL
+	switch a {
+	case AdviceRandom:
+		advice = syscall.MADV_RANDOM
+	case AdviceSequential:
+		advice = syscall.MADV_SEQUENTIAL
+	case AdviceWillNeed:
+		advice = syscall.MADV_WILLNEED
+	}
+	return syscall.Madvise(b, advice)
+}
+
+func lockMapping(b []byte) error {
+	return syscall.Mlock(b)
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package iplist
+
+import "errors"
+
+// mapPopulate is unavailable; WithPrefault touches every page instead.
+const mapPopulate = 0
+
+// adviseMapping ignores the hint; syscall has no portable madvise.
+func adviseMapping(b []byte, a Advice) error {
+	return nil
+}
+
+func lockMapping(b []byte) error {
+	return errors.New("iplist: mlock is not supported on this platform")
+}
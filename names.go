@@ -1,16 +1,22 @@
 package iplist
 
-func defaultCloudSet() map[string]bool {
-	return map[string]bool{
-		"aliyun":       true,
-		"tencent":      true,
-		"huawei":       true,
-		"microsoft":    true,
-		"cloudflare":   true,
-		"googlecloud":  true,
-		"digitalocean": true,
-		"bytedance":    true,
-		"volcengine":   true,
+func defaultProviderKinds() map[string]ProviderKind {
+	return map[string]ProviderKind{
+		"chinatelecom": ProviderKindISP | ProviderKindMobile,
+		"chinaunicom":  ProviderKindISP | ProviderKindMobile,
+		"chinamobile":  ProviderKindISP | ProviderKindMobile,
+		"drpeng":       ProviderKindISP,
+		"cernet":       ProviderKindEducation,
+		"cstnet":       ProviderKindEducation,
+		"aliyun":       ProviderKindCloud,
+		"tencent":      ProviderKindCloud,
+		"huawei":       ProviderKindCloud,
+		"microsoft":    ProviderKindCloud,
+		"cloudflare":   ProviderKindCloud | ProviderKindCDN,
+		"googlecloud":  ProviderKindCloud,
+		"digitalocean": ProviderKindCloud | ProviderKindHosting,
+		"bytedance":    ProviderKindCloud,
+		"volcengine":   ProviderKindCloud,
 	}
 }
 
@@ -0,0 +1,227 @@
+package iplist
+
+import "net/netip"
+
+// Range is an inclusive range of addresses. The zero Range is invalid.
+type Range struct {
+	Start netip.Addr
+	End   netip.Addr
+}
+
+// IsValid reports whether r is set.
+func (r Range) IsValid() bool { return r.Start.IsValid() }
+
+// Contains reports whether addr lies within r.
+func (r Range) Contains(addr netip.Addr) bool {
+	return r.IsValid() && addr.BitLen() == r.Start.BitLen() && r.Start.Compare(addr) <= 0 && addr.Compare(r.End) <= 0
+}
+
+// Prefixes returns the minimal list of CIDRs covering r.
+func (r Range) Prefixes() []netip.Prefix {
+	if !r.IsValid() {
+		return nil
+	}
+	if r.Start.Is4() {
+		ps, _ := rangeToCIDRs(addrU32(r.Start), addrU32(r.End))
+		return ps
+	}
+	ps, _ := rangeToCIDRs6(u128FromAddr(r.Start), u128FromAddr(r.End))
+	return ps
+}
+
+// Ranges holds, per dimension, the range of the entry an IP matched. A
+// dimension without a match has the zero Range. A CN level filled in from a
+// more specific level (see WithoutCNProvinceFill) carries the range of that
+// level's entry.
+type Ranges struct {
+	Country     Range
+	Subdivision Range
+	CNProvince  Range
+	CNCity      Range
+	CNDistrict  Range
+	Provider    Range
+}
+
+func addrU32(a netip.Addr) uint32 {
+	b := a.As4()
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+func range4(lo, hi uint32) Range {
+	return Range{Start: u32Addr(lo), End: u32Addr(hi)}
+}
+
+// span returns the range around ip over which the answer of t does not
+// change: the matching entry, or the gap between entries. ok reports
+// whether ip matched an entry, label is its label.
+func (t *v4Table) span(ip uint32) (label, lo, hi uint32, ok bool) {
+	n := len(t.starts)
+	if n == 0 {
+		return 0, 0, ^uint32(0), false
+	}
+	// Last entry with start <= ip. Entries before the bucket window end
+	// before the bucket, entries after it start after the bucket.
+	wlo, whi := 0, n
+	if t.bucketLo != nil && t.bucketHi != nil {
+		p := ip >> t.bucketShift
+		wlo, whi = int(t.bucketLo[p]), min(int(t.bucketHi[p]), n)
+	}
+	i, j := wlo, whi
+	for i < j {
+		h := (i + j) >> 1
+		if t.starts[h] > ip {
+			j = h
+		} else {
+			i = h + 1
+		}
+	}
+	i--
+	if i >= 0 && ip <= t.ends[i] {
+		if t.dense && t.labels[i] == labelNone {
+			return 0, t.starts[i], t.ends[i], false
+		}
+		return t.labels[i], t.starts[i], t.ends[i], true
+	}
+	lo, hi = 0, ^uint32(0)
+	if i >= 0 {
+		lo = t.ends[i] + 1
+	}
+	if i+1 < n {
+		hi = t.starts[i+1] - 1
+	}
+	return 0, lo, hi, false
+}
+
+// span is the IPv6 counterpart of v4Table.span.
+func (t *v6Table) span(ip u128) (label uint32, lo, hi u128, ok bool) {
+	n := len(t.starts)
+	i, j := 0, n
+	for i < j {
+		h := (i + j) >> 1
+		if ip.less(t.starts[h]) {
+			j = h
+		} else {
+			i = h + 1
+		}
+	}
+	i--
+	if i >= 0 && !t.ends[i].less(ip) {
+		return t.labels[i], t.starts[i], t.ends[i], true
+	}
+	lo, hi = u128{}, hostMask(128)
+	if i >= 0 {
+		lo = t.ends[i].addOne()
+	}
+	if i+1 < n {
+		hi = t.starts[i+1].subOne()
+	}
+	return 0, lo, hi, false
+}
+
+// ranges4 fills dst for ip and returns the largest prefix around ip on
+// which every table gives the same answer. district, city and prov are the
+// labels cnLookup4 returned for ip.
+func (v *v4DB) ranges4(ip uint32, district, city, prov uint32, dst *Ranges) netip.Prefix {
+	lo, hi := uint32(0), ^uint32(0)
+	narrow := func(t *v4Table) (Range, uint32) {
+		label, l, h, ok := t.span(ip)
+		lo, hi = max(lo, l), min(hi, h)
+		if !ok {
+			return Range{}, IDNone
+		}
+		return range4(l, h), label
+	}
+	dst.Country, _ = narrow(&v.country)
+	dst.Subdivision, _ = narrow(&v.subdiv)
+	dst.Provider, _ = narrow(&v.provider)
+	narrow(&v.providerMulti)
+	narrow(&v.special)
+	for i := range v.categories {
+		narrow(&v.categories[i].t4)
+	}
+	rd, dl := narrow(&v.cnDist)
+	rc, cl := narrow(&v.cnCity)
+	rp, pl := narrow(&v.cnProv)
+	dst.CNDistrict, dst.CNCity, dst.CNProvince = cnRanges(district, city, prov, rd, rc, rp, dl, cl, pl)
+	return largestPrefix4(ip, lo, hi)
+}
+
+// ranges6 is the IPv6 counterpart of ranges4.
+func (v *v4DB) ranges6(ip u128, district, city, prov uint32, dst *Ranges) netip.Prefix {
+	lo, hi := u128{}, hostMask(128)
+	narrow := func(t *v6Table) (Range, uint32) {
+		label, l, h, ok := t.span(ip)
+		if lo.less(l) {
+			lo = l
+		}
+		if h.less(hi) {
+			hi = h
+		}
+		if !ok {
+			return Range{}, IDNone
+		}
+		return Range{Start: l.addr(), End: h.addr()}, label
+	}
+	dst.Country, _ = narrow(&v.country6)
+	dst.Subdivision, _ = narrow(&v.subdiv6)
+	dst.Provider, _ = narrow(&v.provider6)
+	narrow(&v.providerMulti6)
+	narrow(&v.special6)
+	for i := range v.categories {
+		narrow(&v.categories[i].t6)
+	}
+	rd, dl := narrow(&v.cnDist6)
+	rc, cl := narrow(&v.cnCity6)
+	rp, pl := narrow(&v.cnProv6)
+	dst.CNDistrict, dst.CNCity, dst.CNProvince = cnRanges(district, city, prov, rd, rc, rp, dl, cl, pl)
+	return largestPrefix6(ip, lo, hi)
+}
+
+// cnRanges attributes the ranges of the CN table entries (rd, rc, rp with
+// labels dl, cl, pl) to the levels cnLookup returned. A level that was
+// filled in from a more specific entry gets that entry's range.
+func cnRanges(district, city, prov uint32, rd, rc, rp Range, dl, cl, pl uint32) (d, c, p Range) {
+	if district != IDNone && district == dl {
+		d = rd
+	}
+	switch {
+	case city == IDNone:
+	case city == cl:
+		c = rc
+	default:
+		c = d
+	}
+	switch {
+	case prov == IDNone:
+	case prov == pl:
+		p = rp
+	case c.IsValid():
+		p = c
+	default:
+		p = d
+	}
+	return d, c, p
+}
+
+// largestPrefix4 returns the shortest prefix containing ip that lies within
+// [lo, hi].
+func largestPrefix4(ip, lo, hi uint32) netip.Prefix {
+	for bits := 0; bits < 32; bits++ {
+		mask := ^uint32(0) >> bits
+		if start := ip &^ mask; start >= lo && start|mask <= hi {
+			return netip.PrefixFrom(u32Addr(start), bits)
+		}
+	}
+	return netip.PrefixFrom(u32Addr(ip), 32)
+}
+
+func largestPrefix6(ip, lo, hi u128) netip.Prefix {
+	for bits := 0; bits < 128; bits++ {
+		m := hostMask(128 - bits)
+		start := u128{Hi: ip.Hi &^ m.Hi, Lo: ip.Lo &^ m.Lo}
+		if !start.less(lo) && !hi.less(start.or(m)) {
+			return netip.PrefixFrom(start.addr(), bits)
+		}
+	}
+	return netip.PrefixFrom(ip.addr(), 128)
+}
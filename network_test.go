@@ -0,0 +1,56 @@
+package iplist
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+)
+
+func TestNetworks(t *testing.T) {
+	b := NewBuilder()
+	_ = b.AddCountry(netip.MustParsePrefix("10.0.0.0/8"), "CN")
+	_ = b.AddCountry(netip.MustParsePrefix("2001:db8::/32"), "CN")
+	_ = b.AddProvider(netip.MustParsePrefix("10.1.0.0/16"), "aliyun", "", ProviderKindCloud)
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.0.0.0/16"), "440000")
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.0.0.0/24"), "440300")
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	db, err := OpenBytes(buf.Bytes(), WithNetworks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rng := func(start, end string) Range {
+		return Range{Start: netip.MustParseAddr(start), End: netip.MustParseAddr(end)}
+	}
+	res, _, _ := db.Lookup("10.0.0.5")
+	if res.Network != netip.MustParsePrefix("10.0.0.0/24") {
+		t.Errorf("10.0.0.5: network %s", res.Network)
+	}
+	if res.Ranges.CNCity != rng("10.0.0.0", "10.0.0.255") || res.Ranges.CNProvince != rng("10.0.0.0", "10.0.255.255") || res.Ranges.Provider.IsValid() {
+		t.Errorf("10.0.0.5: ranges %+v", res.Ranges)
+	}
+	if ps := res.Ranges.Country.Prefixes(); len(ps) != 1 || ps[0] != netip.MustParsePrefix("10.0.0.0/8") {
+		t.Errorf("10.0.0.5: country prefixes %v", ps)
+	}
+	for ip, want := range map[string]string{
+		"10.1.2.3":      "10.1.0.0/16",
+		"10.2.0.1":      "10.2.0.0/15",
+		"11.0.0.1":      "11.0.0.0/8", // no match
+		"2001:db8::1":   "2001:db8::/32",
+		"2001:db9::1:1": "2001:db9::/32",
+	} {
+		ids, _, _ := db.LookupIDs(ip)
+		if ids.Network != netip.MustParsePrefix(want) {
+			t.Errorf("%s: network %s, want %s", ip, ids.Network, want)
+		}
+	}
+
+	plain := buildTestDB(t, b)
+	if res, _, _ := plain.Lookup("10.0.0.5"); res.Network.IsValid() || res.Ranges.Country.IsValid() {
+		t.Errorf("without WithNetworks: %+v", res)
+	}
+}
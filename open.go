@@ -1,8 +1,12 @@
 package iplist
 
 import (
+	"bytes"
 	"encoding/binary"
+	"io"
+	"math/bits"
 	"os"
+	"strings"
 	"syscall"
 	"unsafe"
 )
@@ -19,12 +23,14 @@ func init() {
 }
 
 type v4Table struct {
-	starts     []uint32
-	ends       []uint32
-	labels     []uint32
-	dense      bool
-	bucketLo16 []uint32 // len 65536; first i with ends[i]   >= (p<<16)
-	bucketHi16 []uint32 // len 65536; first i with starts[i] >= ((p+1)<<16)
+	starts      []uint32
+	ends        []uint32
+	labels      []uint32
+	dense       bool
+	bucketLo    []uint32 // first i with ends[i]   >= (p<<bucketShift)
+	bucketHi    []uint32 // first i with starts[i] >= ((p+1)<<bucketShift)
+	bucketShift uint8    // 32 - bucketBits(len(starts))
+	postings    []uint32 // see postings.go; nil if the file has none
 }
 
 func (t *v4Table) detectDense() {
@@ -55,23 +61,66 @@ func (t *v4Table) detectDense() {
 	t.dense = t.ends[len(t.ends)-1] == maxU32
 }
 
-func (t *v4Table) buildBuckets16() {
-	if len(t.starts) == 0 {
-		t.bucketLo16 = nil
-		t.bucketHi16 = nil
+func (t *v4Table) buildBuckets() {
+	width := bucketBits(len(t.starts))
+	if width == 0 {
+		t.bucketLo, t.bucketHi = nil, nil
 		return
 	}
-	const size = 1 << 16
+	t.bucketLo, t.bucketHi = buckets(t.starts, t.ends, width)
+	t.bucketShift = uint8(32 - width)
+}
+
+// initBuckets validates the persisted bucket index of t, or computes one if
+// the file has none.
+func (t *v4Table) initBuckets() error {
+	if t.bucketLo == nil && t.bucketHi == nil {
+		t.buildBuckets()
+		return nil
+	}
+	n := len(t.bucketLo)
+	if n != len(t.bucketHi) || n < 1<<minBucketBits || n > 1<<maxBucketBits || n&(n-1) != 0 {
+		return ErrInvalidDB
+	}
+	t.bucketShift = uint8(32 - bits.TrailingZeros(uint(n)))
+	return nil
+}
+
+const (
+	minBucketBits = 8
+	maxBucketBits = 16
+)
+
+// bucketBits returns the number of leading address bits the bucket index of
+// a table with n entries is keyed on, or 0 if the table is too small to need
+// one. It grows with the table so that a bucket holds one or two entries on
+// average and the index stays smaller than the table itself.
+func bucketBits(n int) int {
+	if n < 1<<minBucketBits {
+		return 0
+	}
+	return min(bits.Len(uint(n))-1, maxBucketBits)
+}
+
+// buckets computes the bucket index of a sorted, disjoint table, keyed on
+// the top bits of the address. It is persisted by Build and only recomputed
+// for older files.
+func buckets(starts, ends []uint32, width int) (lo, hi []uint32) {
+	if len(starts) == 0 {
+		return nil, nil
+	}
+	size := 1 << width
+	shift := 32 - width
 	startGE := make([]uint32, size+1)
 	endGE := make([]uint32, size+1)
 
 	// Build startGE: first index with start >= prefixStart.
 	{
 		i := 0
-		n := len(t.starts)
+		n := len(starts)
 		for p := 0; p <= size; p++ {
-			key := uint32(p) << 16
-			for i < n && t.starts[i] < key {
+			key := uint64(p) << shift
+			for i < n && uint64(starts[i]) < key {
 				i++
 			}
 			startGE[p] = uint32(i)
@@ -80,25 +129,33 @@ func (t *v4Table) buildBuckets16() {
 	// Build endGE: first index with end >= prefixStart.
 	{
 		i := 0
-		n := len(t.ends)
+		n := len(ends)
 		for p := 0; p <= size; p++ {
-			key := uint32(p) << 16
-			for i < n && t.ends[i] < key {
+			key := uint64(p) << shift
+			for i < n && uint64(ends[i]) < key {
 				i++
 			}
 			endGE[p] = uint32(i)
 		}
 	}
 
-	lo := make([]uint32, size)
-	hi := make([]uint32, size)
+	lo = make([]uint32, size)
+	hi = make([]uint32, size)
 	for p := 0; p < size; p++ {
 		lo[p] = endGE[p]
 		hi[p] = startGE[p+1]
 	}
 	// Keep only the compact bucket boundaries.
-	t.bucketLo16 = lo
-	t.bucketHi16 = hi
+	return lo, hi
+}
+
+// v6Table is the IPv6 counterpart of v4Table.
+// Entries are sorted by start and do not overlap.
+type v6Table struct {
+	starts   []u128
+	ends     []u128
+	labels   []uint32
+	postings []uint32
 }
 
 type v4DB struct {
@@ -109,17 +166,63 @@ type v4DB struct {
 	countryLabels  []label2
 	cnLabels       []label2
 	providerLabels []providerLabel
+	subdivLabels   []label2 // ISO 3166-2; absent in files before subdivisions
+	specialLabels  []uint32 // special set names; index is the mask bit
 
 	country  v4Table
 	cnProv   v4Table
 	cnCity   v4Table
+	cnDist   v4Table
 	provider v4Table
-
-	providerByKey     map[string]uint32
+	subdiv   v4Table
+	special  v4Table
+	// providerMulti holds the ranges covered by several providers, see
+	// providerset.go.
+	providerMulti v4Table
+
+	country6  v6Table
+	cnProv6   v6Table
+	cnCity6   v6Table
+	cnDist6   v6Table
+	provider6 v6Table
+	subdiv6   v6Table
+	special6  v6Table
+
+	providerMulti6 v6Table
+	providerSets   []uint32
+	providerSelf   []uint32
+
+	// Custom categories, indexed by table id - tableCustomBase.
+	categories      []customCategory
+	categoryRecords []categoryRecord
+	categoryByName  map[string]int
+
+	providerByKey     map[string]uint32 // keys and aliases
 	providerKindByKey map[string]ProviderKind
+
+	specialSets   []string
+	specialByMask map[uint32][]string
+
+	// cnCityOf and cnProvOf map a CN label to the label of its city and
+	// province by admin-code prefix (IDNone if there is none).
+	// fillCNProvince enables their use in lookups.
+	cnCityOf       []uint32
+	cnProvOf       []uint32
+	fillCNProvince bool
+
+	networks bool // WithNetworks
+
+	cnMigrations []cnMigration // sorted by Old
+	cnByCode     map[string]uint32
+
+	providerInfo    []byte // raw secProviderInfo section, if any
+	providerRecords []providerRecord
+
+	buildTime int64  // unix seconds from the file header
+	metadata  []byte // raw metadata section, if any
 }
 
-func open(path string) (*DB, error) {
+func open(path string, cfg *openConfig) (*DB, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -137,18 +240,37 @@ func open(path string) (*DB, error) {
 		return nil, ErrInvalidDB
 	}
 
-	data, err := mmapFile(f, int(size))
+	if cfg.heapCopy {
+		data := alignedBytes(int(size))
+		_, err := io.ReadFull(io.NewSectionReader(f, 0, size), data)
+		_ = f.Close()
+		if err != nil {
+			return nil, err
+		}
+		return openData(data, nil, cfg)
+	}
+
+	data, err := mmapFile(f, int(size), cfg)
 	if err != nil {
 		_ = f.Close()
 		return nil, err
 	}
+	return openData(data, f, cfg)
+}
 
-	db := &DB{raw: f, data: data}
-	v4, err := parseV4(data)
+// openData parses data into a DB. A non-nil f means data is an mmap of f.
+func openData(data []byte, f *os.File, cfg *openConfig) (*DB, error) {
+	if len(data) < headerSize {
+		return nil, ErrInvalidDB
+	}
+	db := &DB{raw: f, data: data, mapped: f != nil, heapStrings: f == nil || cfg.heapStrings}
+	v4, err := parseV4(data, cfg)
 	if err != nil {
 		_ = db.close()
 		return nil, err
 	}
+	v4.fillCNProvince = !cfg.noCNProvinceFill
+	v4.networks = cfg.networks
 	db.v4 = v4
 	return db, nil
 }
@@ -156,7 +278,7 @@ func open(path string) (*DB, error) {
 func (db *DB) close() error {
 	var firstErr error
 	hasErr := false
-	if len(db.data) > 0 {
+	if len(db.data) > 0 && db.mapped {
 		if err := syscall.Munmap(db.data); err != nil {
 			if !hasErr {
 				firstErr = err
@@ -180,16 +302,57 @@ func (db *DB) close() error {
 	return firstErr
 }
 
-func mmapFile(f *os.File, size int) ([]byte, error) {
+func mmapFile(f *os.File, size int, cfg *openConfig) ([]byte, error) {
+	prot := syscall.PROT_READ
+	flags := syscall.MAP_SHARED
+	if cfg.mapPrivate {
+		flags = syscall.MAP_PRIVATE
+	}
+	if !nativeLittleEndian {
+		// Tables are byte-swapped in place; keep the writes out of the file.
+		prot |= syscall.PROT_WRITE
+		flags = syscall.MAP_PRIVATE
+	}
+	if cfg.prefault {
+		flags |= mapPopulate
+	}
 	// syscall.Mmap is still fine on Linux; keep the implementation dependency-free.
-	data, err := syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, prot, flags)
 	if err != nil {
 		return nil, err
 	}
+	for _, a := range cfg.advice {
+		if err := adviseMapping(data, a); err != nil {
+			_ = syscall.Munmap(data)
+			return nil, err
+		}
+	}
+	if cfg.prefault && mapPopulate == 0 {
+		touchPages(data)
+	}
+	if cfg.mlock {
+		if err := lockMapping(data); err != nil {
+			_ = syscall.Munmap(data)
+			return nil, err
+		}
+	}
 	return data, nil
 }
 
-func parseV4(b []byte) (*v4DB, error) {
+// touchSink keeps touchPages' reads from being optimized away.
+var touchSink byte
+
+// touchPages reads one byte per page to fault the mapping in.
+func touchPages(b []byte) {
+	var x byte
+	step := os.Getpagesize()
+	for i := 0; i < len(b); i += step {
+		x ^= b[i]
+	}
+	touchSink = x
+}
+
+func parseV4(b []byte, cfg *openConfig) (*v4DB, error) {
 	if len(b) < headerSize {
 		return nil, ErrInvalidDB
 	}
@@ -197,7 +360,14 @@ func parseV4(b []byte) (*v4DB, error) {
 		return nil, ErrInvalidDB
 	}
 	ver := binary.LittleEndian.Uint16(b[4:6])
-	if ver != version2 {
+	switch ver {
+	case version2:
+		if cfg.publicKey != nil {
+			return nil, &SignatureError{Reason: "file is not signed"}
+		}
+	case version3:
+		return parseV4v3(b, cfg)
+	default:
 		return nil, ErrInvalidDB
 	}
 
@@ -210,15 +380,19 @@ func parseV4(b []byte) (*v4DB, error) {
 	if err != nil {
 		return nil, err
 	}
+	if cfg.heapStrings {
+		stringsData = bytes.Clone(stringsData)
+	}
 
 	v := &v4DB{stringsData: stringsData, stringsStart: stringsStart, stringsEnd: stringsEnd}
+	v.buildTime = int64(binary.LittleEndian.Uint64(b[8:16]))
 	return parseV4v2(v, b)
 }
 
 func parseV4v2(v *v4DB, b []byte) (*v4DB, error) {
 	secOff := int(binary.LittleEndian.Uint32(b[24:28]))
 	secSize := int(binary.LittleEndian.Uint32(b[28:32]))
-	if secOff <= 0 || secSize <= 0 || secOff+secSize > len(b) {
+	if secOff <= 0 || secSize < sectionHeaderSize || secOff+secSize > len(b) {
 		return nil, ErrInvalidDB
 	}
 	sec := b[secOff : secOff+secSize]
@@ -335,39 +509,151 @@ func parseV4v2(v *v4DB, b []byte) (*v4DB, error) {
 		return nil, err
 	}
 
+	if err := parseV6Tables(v, b, sec[secV6TablesOff:]); err != nil {
+		return nil, err
+	}
+	for _, t := range [...]*v4Table{&v.country, &v.cnProv, &v.cnCity, &v.provider} {
+		t.detectDense()
+	}
+	if err := v.finish(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// finish validates the parsed IPv4 tables and builds the in-memory indexes.
+func (v *v4DB) finish() error {
 	if len(v.country.starts) != len(v.country.ends) || len(v.country.starts) != len(v.country.labels) {
-		return nil, ErrInvalidDB
+		return ErrInvalidDB
 	}
 	if len(v.cnProv.starts) != len(v.cnProv.ends) || len(v.cnProv.starts) != len(v.cnProv.labels) {
-		return nil, ErrInvalidDB
+		return ErrInvalidDB
 	}
 	if len(v.cnCity.starts) != len(v.cnCity.ends) || len(v.cnCity.starts) != len(v.cnCity.labels) {
-		return nil, ErrInvalidDB
+		return ErrInvalidDB
 	}
 	if len(v.provider.starts) != len(v.provider.ends) || len(v.provider.starts) != len(v.provider.labels) {
-		return nil, ErrInvalidDB
+		return ErrInvalidDB
+	}
+	if len(v.cnDist.starts) != len(v.cnDist.ends) || len(v.cnDist.starts) != len(v.cnDist.labels) {
+		return ErrInvalidDB
+	}
+	if len(v.subdiv.starts) != len(v.subdiv.ends) || len(v.subdiv.starts) != len(v.subdiv.labels) {
+		return ErrInvalidDB
+	}
+	if len(v.special.starts) != len(v.special.ends) || len(v.special.starts) != len(v.special.labels) {
+		return ErrInvalidDB
+	}
+	if len(v.providerMulti.starts) != len(v.providerMulti.ends) || len(v.providerMulti.starts) != len(v.providerMulti.labels) {
+		return ErrInvalidDB
+	}
+	for _, id := range [...]uint16{tableCountry, tableCNProv, tableCNCity, tableCNDist, tableProvider, tableSubdiv, tableSpecial, tableProviderMulti} {
+		// Files written by Build carry the bucket index of every table
+		// large enough to need one; older files get it computed here.
+		if err := v.table4(id).initBuckets(); err != nil {
+			return err
+		}
 	}
-	v.country.detectDense()
-	v.cnProv.detectDense()
-	v.cnCity.detectDense()
-	v.provider.detectDense()
-	v.country.buildBuckets16()
-	v.cnProv.buildBuckets16()
-	v.cnCity.buildBuckets16()
-	v.provider.buildBuckets16()
 
 	v.providerByKey = make(map[string]uint32, len(v.providerLabels))
 	v.providerKindByKey = make(map[string]ProviderKind, len(v.providerLabels))
 	for i, pl := range v.providerLabels {
 		key := v.str(pl.Key)
 		if key == "" {
-			return nil, ErrInvalidDB
+			return ErrInvalidDB
 		}
 		v.providerByKey[key] = uint32(i)
 		v.providerKindByKey[key] = ProviderKind(pl.Kind)
 	}
+	if err := v.initProviderRecords(); err != nil {
+		return err
+	}
+	if err := v.initProviderSets(); err != nil {
+		return err
+	}
+	if err := v.initCategories(); err != nil {
+		return err
+	}
+	v.initCNParents()
+	return v.initSpecial()
+}
 
-	return v, nil
+// initCNParents derives the city and province of each CN label from its
+// admin-code prefix: 440305 belongs to 440300, which belongs to 440000.
+func (v *v4DB) initCNParents() {
+	byCode := make(map[string]uint32, len(v.cnLabels))
+	for i := range v.cnLabels {
+		code, _ := v.cnLabel(uint32(i))
+		byCode[code] = uint32(i)
+	}
+	parent := func(code, suffix string, n int) uint32 {
+		if p, ok := byCode[code[:n]+suffix]; ok && p != byCode[code] {
+			return p
+		}
+		return IDNone
+	}
+	v.cnByCode = byCode
+	v.cnCityOf = make([]uint32, len(v.cnLabels))
+	v.cnProvOf = make([]uint32, len(v.cnLabels))
+	for i := range v.cnLabels {
+		v.cnCityOf[i], v.cnProvOf[i] = IDNone, IDNone
+		code, _ := v.cnLabel(uint32(i))
+		if len(code) != 6 || strings.HasSuffix(code, "0000") {
+			continue
+		}
+		v.cnProvOf[i] = parent(code, "0000", 2)
+		if !strings.HasSuffix(code, "00") {
+			v.cnCityOf[i] = parent(code, "00", 4)
+		}
+	}
+}
+
+// cnUp maps label through one of cnCityOf/cnProvOf.
+func (v *v4DB) cnUp(up []uint32, label uint32) uint32 {
+	if label >= uint32(len(up)) {
+		return IDNone
+	}
+	return up[label]
+}
+
+func parseV6Tables(v *v4DB, b []byte, sec []byte) error {
+	tables := [...]*v6Table{&v.country6, &v.cnProv6, &v.cnCity6, &v.provider6}
+	for i, t := range tables {
+		h := sec[i*16 : i*16+16]
+		startsOff := int(binary.LittleEndian.Uint32(h[0:4]))
+		endsOff := int(binary.LittleEndian.Uint32(h[4:8]))
+		lblsOff := int(binary.LittleEndian.Uint32(h[8:12]))
+		cnt := int(binary.LittleEndian.Uint32(h[12:16]))
+		if cnt == 0 {
+			continue
+		}
+		// u128 columns are read as pairs of u64 and must be 8-byte aligned.
+		if startsOff%8 != 0 || endsOff%8 != 0 {
+			return ErrInvalidDB
+		}
+		if !nativeLittleEndian {
+			if err := swapU64Words(b, startsOff, cnt*2); err != nil {
+				return err
+			}
+			if err := swapU64Words(b, endsOff, cnt*2); err != nil {
+				return err
+			}
+			if err := swapU32Words(b, lblsOff, cnt); err != nil {
+				return err
+			}
+		}
+		var err error
+		if t.starts, err = sliceFixed[u128](b, startsOff, cnt); err != nil {
+			return err
+		}
+		if t.ends, err = sliceFixed[u128](b, endsOff, cnt); err != nil {
+			return err
+		}
+		if t.labels, err = sliceFixed[uint32](b, lblsOff, cnt); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func swapFixedTablesLEToHost(
@@ -454,6 +740,26 @@ func swapU32Words(b []byte, off int, count int) error {
 	return nil
 }
 
+func swapU64Words(b []byte, off int, count int) error {
+	if off < 0 || count < 0 {
+		return ErrInvalidDB
+	}
+	end := off + count*8
+	if off == 0 && count == 0 {
+		return nil
+	}
+	if off <= 0 || end > len(b) {
+		return ErrInvalidDB
+	}
+	for i := off; i < end; i += 8 {
+		b[i+0], b[i+7] = b[i+7], b[i+0]
+		b[i+1], b[i+6] = b[i+6], b[i+1]
+		b[i+2], b[i+5] = b[i+5], b[i+2]
+		b[i+3], b[i+4] = b[i+4], b[i+3]
+	}
+	return nil
+}
+
 func parseStringsTable(b []byte) (data []byte, start []uint32, end []uint32, err error) {
 	if len(b) < 4 {
 		return nil, nil, nil, ErrInvalidDB
@@ -530,6 +836,16 @@ func (v *v4DB) cnLabel(idx uint32) (code, name string) {
 	return
 }
 
+func (v *v4DB) subdivLabel(idx uint32) (code, name string) {
+	if idx >= uint32(len(v.subdivLabels)) {
+		return "", ""
+	}
+	l := v.subdivLabels[idx]
+	code = v.str(l.Code)
+	name = v.str(l.Name)
+	return
+}
+
 func (v *v4DB) providerLabel(idx uint32) (key, name string, kind ProviderKind) {
 	if idx >= uint32(len(v.providerLabels)) {
 		return "", "", ProviderKindUnknown
@@ -0,0 +1,151 @@
+package iplist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand/v2"
+	"net/netip"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testdata/v2.db was written by the format version 2 builder (before IPv6 and
+// the section directory) from a handful of CIDR files.
+func TestOpenV2(t *testing.T) {
+	db, err := Open("testdata/v2.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		ip                          string
+		country, prov, city, vendor string
+		kind                        ProviderKind
+	}{
+		{"1.0.1.1", "CN", "440000", "", "chinatelecom", ProviderKindISP},
+		{"1.0.2.1", "CN", "440000", "440100", "aliyun", ProviderKindCloud},
+		{"1.0.3.1", "CN", "", "", "", 0},
+		{"8.8.8.8", "US", "", "", "aliyun", ProviderKindCloud},
+	}
+	for _, tt := range tests {
+		res, ok, err := db.Lookup(tt.ip)
+		if err != nil || !ok {
+			t.Fatalf("%s: %v %v", tt.ip, ok, err)
+		}
+		if res.CountryCode != tt.country || res.CNProvinceCode != tt.prov || res.CNCityCode != tt.city ||
+			res.ProviderKey != tt.vendor || res.ProviderKind != tt.kind {
+			t.Errorf("%s: got %s/%s/%s/%s/%v", tt.ip, res.CountryCode, res.CNProvinceCode, res.CNCityCode, res.ProviderKey, res.ProviderKind)
+		}
+	}
+	if _, ok, _ := db.Lookup("2001:db8::1"); ok {
+		t.Error("v2 file matched an IPv6 address")
+	}
+
+	md, err := db.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Unix(1792150031, 0).UTC(); !md.BuildTime.Equal(want) || md.DataRevision != "" || len(md.Counts) != 0 {
+		t.Errorf("Metadata = %+v, want only BuildTime %v", md, want)
+	}
+
+	got, kind, err := db.ProviderIPs("aliyun")
+	if want := []string{"1.0.2.0/24", "8.8.8.0/24"}; err != nil || kind != ProviderKindCloud || !slices.Equal(got, want) {
+		t.Errorf("ProviderIPs(aliyun) = %v, %v, %v; want %v", got, kind, err, want)
+	}
+	if got, err := db.CountryIPs("CN"); !slices.Equal(got, []string{"1.0.1.0/24", "1.0.2.0/23"}) || err != nil {
+		t.Errorf("CountryIPs(CN) = %v, %v", got, err)
+	}
+}
+
+// TestPersistedBuckets checks that the bucket index stored by Build gives
+// the same lookups as one rebuilt at Open for a file without it.
+func TestPersistedBuckets(t *testing.T) {
+	b := NewBuilder()
+	codes := []string{"CN", "US", "JP", "DE"}
+	var starts []uint32
+	for i := uint32(0); i < 3000; i++ {
+		start := i << 20
+		starts = append(starts, start)
+		p := netip.PrefixFrom(u32Addr(start), 16+int(i%9))
+		_ = b.AddCountry(p, codes[i%4])
+		if i%3 == 0 {
+			_ = b.AddProvider(p, "carrier", "", ProviderKindISP)
+		}
+		if i%5 == 0 {
+			_ = b.AddCNRegion(p, "440100")
+		}
+		if i%50 == 0 {
+			_ = b.AddSpecial(p, "anycast")
+		}
+	}
+	data := testDBBytes(t, b)
+
+	// Hide the bucket sections behind an unknown type and re-seal the file.
+	stripped := bytes.Clone(data)
+	dirOff := int(binary.LittleEndian.Uint32(stripped[16:20]))
+	dirCount := int(binary.LittleEndian.Uint32(stripped[20:24]))
+	hidden := 0
+	for i := 0; i < dirCount; i++ {
+		e := stripped[dirOff+i*sectionEntrySize:]
+		if typ := binary.LittleEndian.Uint16(e[0:2]); typ == secBucketLo || typ == secBucketHi {
+			binary.LittleEndian.PutUint16(e[0:2], 0x7fff)
+			hidden++
+		}
+	}
+	sealFile(stripped, nil, nil)
+
+	persisted, err := OpenBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer persisted.Close()
+	// The index grows with the table; small tables (special) have none.
+	indexed := 0
+	for _, id := range []uint16{tableCountry, tableCNProv, tableCNCity, tableProvider, tableSpecial} {
+		tab := persisted.v4.table4(id)
+		width := bucketBits(len(tab.starts))
+		if width == 0 {
+			if tab.bucketLo != nil {
+				t.Errorf("table %d (%d entries) has a bucket index", id, len(tab.starts))
+			}
+			continue
+		}
+		indexed++
+		if len(tab.bucketLo) != 1<<width || int(tab.bucketShift) != 32-width {
+			t.Errorf("table %d: %d buckets, shift %d", id, len(tab.bucketLo), tab.bucketShift)
+		}
+	}
+	if indexed < 3 || hidden != 2*indexed {
+		t.Fatalf("%d bucket sections in the file for %d indexed tables", hidden, indexed)
+	}
+	rebuilt, err := OpenBytes(stripped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rebuilt.Close()
+	if persisted.v4.country.dense != rebuilt.v4.country.dense {
+		t.Errorf("dense flag: persisted %v, rebuilt %v", persisted.v4.country.dense, rebuilt.v4.country.dense)
+	}
+
+	var probes []uint32
+	for _, s := range starts {
+		probes = append(probes, s-1, s, s+1, s+0xff, s+0x100)
+	}
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 20000 {
+		probes = append(probes, r.Uint32())
+	}
+	probes = append(probes, 0, 0xffffffff)
+	for _, p := range probes {
+		addr := u32Addr(p)
+		want, wantOK, _ := persisted.LookupAddr(addr)
+		got, gotOK, _ := rebuilt.LookupAddr(addr)
+		if gotOK != wantOK || !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: rebuilt %+v (%v), persisted %+v (%v)", addr, got, gotOK, want, wantOK)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package iplist
+
+import "crypto/ed25519"
+
+// OpenOption configures Open.
+type OpenOption func(*openConfig)
+
+type openConfig struct {
+	publicKey  ed25519.PublicKey
+	noChecksum bool
+
+	heapCopy   bool
+	mapPrivate bool
+	prefault   bool
+	mlock      bool
+	advice     []Advice
+
+	heapStrings bool
+
+	noCNProvinceFill bool
+	networks         bool
+}
+
+func newOpenConfig(opts []OpenOption) *openConfig {
+	cfg := &openConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithPublicKey requires the file to carry a valid ed25519 signature made with
+// the matching private key (see WithSigningKey). Open returns a *SignatureError
+// for unsigned files and on mismatch.
+func WithPublicKey(pub ed25519.PublicKey) OpenOption {
+	return func(c *openConfig) { c.publicKey = pub }
+}
+
+// WithoutChecksum skips checksum verification and the validation of the
+// reverse-query postings. Open then costs O(1) in the file size, but
+// corrupted files are only caught by bounds checks.
+func WithoutChecksum() OpenOption {
+	return func(c *openConfig) { c.noChecksum = true }
+}
+
+// WithHeapStrings copies the string table and the provider sets into the Go
+// heap. Strings returned in Result and by the *ByID helpers, and
+// ResultIDs.ProviderIDs, then stay valid after Close, instead of pointing
+// into the mapping. Both tables are a few KB.
+func WithHeapStrings() OpenOption {
+	return func(c *openConfig) { c.heapStrings = true }
+}
+
+// WithoutCNProvinceFill keeps the old CN lookup behaviour: only the most
+// specific matching level (district, city or province) is set and the levels
+// above it stay empty (IDNone in ResultIDs).
+func WithoutCNProvinceFill() OpenOption {
+	return func(c *openConfig) { c.noCNProvinceFill = true }
+}
+
+// WithNetworks makes lookups fill Result.Ranges and Result.Network (and the
+// same fields of ResultIDs). It roughly doubles the cost of a lookup, so it
+// is off by default.
+func WithNetworks() OpenOption {
+	return func(c *openConfig) { c.networks = true }
+}
+
+// Advice is a memory-access hint for a mapped database (madvise).
+type Advice int
+
+const (
+	AdviceNormal Advice = iota
+	AdviceRandom
+	AdviceSequential
+	AdviceWillNeed
+)
+
+// WithHeapCopy reads the whole file into the Go heap instead of mapping it.
+// The DB is then immune to the file being rewritten or truncated under the
+// process, at the cost of a private copy per process.
+// Mapping options (WithMapPrivate, WithPrefault, WithMlock, WithAdvice) are ignored.
+func WithHeapCopy() OpenOption {
+	return func(c *openConfig) { c.heapCopy = true }
+}
+
+// WithMapPrivate maps the file with MAP_PRIVATE instead of MAP_SHARED.
+// Pages are still read from the file on demand; on Linux a page the process
+// has not touched yet may reflect later writes to the file. Use WithHeapCopy
+// for full isolation.
+func WithMapPrivate() OpenOption {
+	return func(c *openConfig) { c.mapPrivate = true }
+}
+
+// WithPrefault faults the whole mapping in during Open (MAP_POPULATE on Linux,
+// touching every page elsewhere), so first lookups do not page-fault.
+func WithPrefault() OpenOption {
+	return func(c *openConfig) { c.prefault = true }
+}
+
+// WithMlock locks the mapping into RAM (mlock, Linux only). It usually requires
+// CAP_IPC_LOCK or a sufficient RLIMIT_MEMLOCK; Open fails otherwise.
+func WithMlock() OpenOption {
+	return func(c *openConfig) { c.mlock = true }
+}
+
+// WithAdvice applies madvise hints to the mapping, in order.
+// Hints are ignored on platforms other than Linux.
+func WithAdvice(advice ...Advice) OpenOption {
+	return func(c *openConfig) { c.advice = append(c.advice, advice...) }
+}
@@ -0,0 +1,80 @@
+package iplist
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestWithoutChecksum(t *testing.T) {
+	data := testDBBytes(t, newVerifyBuilder())
+	bad := bytes.Clone(data)
+	bad[hdrCRCOff] ^= 0xff // only the stored CRC is wrong
+
+	if _, err := OpenBytes(bad); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("default: %v, want ErrChecksumMismatch", err)
+	}
+	db, err := OpenBytes(bad, WithoutChecksum())
+	if err != nil {
+		t.Fatalf("WithoutChecksum: %v", err)
+	}
+	defer db.Close()
+	if res, _, _ := db.Lookup("1.0.0.1"); res.CountryCode != "CN" {
+		t.Errorf("country %q, want CN", res.CountryCode)
+	}
+}
+
+func TestWithHeapStrings(t *testing.T) {
+	db, err := Open(countryTestDB(t, "CN"), WithHeapStrings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, ok, err := db.Lookup("1.0.0.1")
+	if !ok || err != nil {
+		t.Fatalf("lookup: %v %v", ok, err)
+	}
+	ids, _, _ := db.LookupIDs("1.0.0.1")
+	code, name, _ := db.CountryByID(ids.CountryID)
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// The file is unmapped now; the strings must not point into it.
+	if res.CountryCode != "CN" || res.CountryName != name || code != "CN" {
+		t.Errorf("after Close: %q (%q), ByID %q (%q)", res.CountryCode, res.CountryName, code, name)
+	}
+}
+
+func TestWithHeapCopy(t *testing.T) {
+	path := countryTestDB(t, "CN")
+	db, err := Open(path, WithHeapCopy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// Truncating the file would fault a mapped DB.
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if res, _, _ := db.Lookup("1.0.0.1"); res.CountryCode != "CN" {
+		t.Errorf("country %q, want CN", res.CountryCode)
+	}
+}
+
+func TestMappingOptions(t *testing.T) {
+	path := countryTestDB(t, "CN")
+	for name, opt := range map[string]OpenOption{
+		"WithMapPrivate": WithMapPrivate(),
+		"WithPrefault":   WithPrefault(),
+		"WithAdvice":     WithAdvice(AdviceRandom, AdviceWillNeed),
+	} {
+		db, err := Open(path, opt)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if res, _, _ := db.Lookup("1.0.0.1"); res.CountryCode != "CN" {
+			t.Errorf("%s: country %q, want CN", name, res.CountryCode)
+		}
+		db.Close()
+	}
+}
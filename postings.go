@@ -0,0 +1,236 @@
+package iplist
+
+import (
+	"bufio"
+	"io"
+	"net/netip"
+	"slices"
+)
+
+// Reverse queries (CountryIPs, ProviderIPs, Select, ...) need every entry of
+// one label. Build stores a posting list per table and address family, so
+// they cost time proportional to the label's entries rather than a scan of
+// the whole table:
+//
+//	secPostings4 / secPostings6, Table = table id:
+//	  n, offsets[0..n], entry indices
+//
+// The entries listed under key k are indices[offsets[k]:offsets[k+1]], in
+// address order. Keys are label ids; for tableProviderMulti they are the
+// provider labels, and an entry is listed under every provider of its set.
+// tableSpecial, whose labels are bitmasks, has no postings. Files without
+// postings fall back to scanning the labels.
+
+// postingKeys describes the keys of a table's postings.
+type postingKeys struct {
+	n    int                         // number of keys
+	keys func(label uint32) []uint32 // keys of an entry; nil: the label itself
+}
+
+// buildPostings encodes the postings of a table with the given labels.
+func buildPostings(labels []uint32, pk *postingKeys) []uint32 {
+	keysOf := func(l uint32) []uint32 {
+		if pk.keys != nil {
+			return pk.keys(l)
+		}
+		if l >= uint32(pk.n) {
+			return nil
+		}
+		return []uint32{l}
+	}
+	out := make([]uint32, 1+pk.n+1)
+	out[0] = uint32(pk.n)
+	offsets := out[1:]
+	for _, l := range labels {
+		for _, k := range keysOf(l) {
+			offsets[k+1]++
+		}
+	}
+	for k := 0; k < pk.n; k++ {
+		offsets[k+1] += offsets[k]
+	}
+	idx := make([]uint32, offsets[pk.n])
+	next := slices.Clone(offsets[:pk.n])
+	for i, l := range labels {
+		for _, k := range keysOf(l) {
+			idx[next[k]] = uint32(i)
+			next[k]++
+		}
+	}
+	return append(out, idx...)
+}
+
+// validPostings reports whether p is well-formed for a table of n entries.
+func validPostings(p []uint32, n int) bool {
+	if len(p) == 0 {
+		return true
+	}
+	k := uint64(p[0])
+	if uint64(len(p)) < k+2 {
+		return false
+	}
+	offsets, idx := p[1:k+2], p[k+2:]
+	if offsets[0] != 0 || uint64(offsets[k]) != uint64(len(idx)) {
+		return false
+	}
+	for i := uint64(0); i < k; i++ {
+		if offsets[i] > offsets[i+1] {
+			return false
+		}
+	}
+	for _, i := range idx {
+		if uint64(i) >= uint64(n) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkPostings validates the postings of every table.
+func (v *v4DB) checkPostings() error {
+	t4 := []*v4Table{&v.country, &v.cnProv, &v.cnCity, &v.cnDist, &v.provider, &v.subdiv, &v.special, &v.providerMulti}
+	t6 := []*v6Table{&v.country6, &v.cnProv6, &v.cnCity6, &v.cnDist6, &v.provider6, &v.subdiv6, &v.special6, &v.providerMulti6}
+	for i := range v.categories {
+		t4 = append(t4, &v.categories[i].t4)
+		t6 = append(t6, &v.categories[i].t6)
+	}
+	for _, t := range t4 {
+		if !validPostings(t.postings, len(t.starts)) {
+			return ErrInvalidDB
+		}
+	}
+	for _, t := range t6 {
+		if !validPostings(t.postings, len(t.starts)) {
+			return ErrInvalidDB
+		}
+	}
+	return nil
+}
+
+// posting returns the entry indices listed under key in p. Postings opened
+// WithoutChecksum are not validated, so malformed ones yield nil instead of
+// panicking; the caller still bounds-checks the indices.
+func posting(p []uint32, key uint32) []uint32 {
+	n := uint64(p[0])
+	if uint64(key) >= n || uint64(len(p)) < n+2 {
+		return nil
+	}
+	lo, hi := n+2+uint64(p[1+key]), n+2+uint64(p[1+key+1])
+	if lo > hi || hi > uint64(len(p)) {
+		return nil
+	}
+	return p[lo:hi]
+}
+
+// appendKey appends the entries of t listed under key to dst. Without
+// postings it scans for the entries whose label satisfies match.
+func (t *v4Table) appendKey(dst []entry, key uint32, match func(label uint32) bool) []entry {
+	if t.postings != nil {
+		for _, i := range posting(t.postings, key) {
+			if int(i) < len(t.starts) {
+				dst = append(dst, entry{Start: t.starts[i], End: t.ends[i]})
+			}
+		}
+		return dst
+	}
+	for i, l := range t.labels {
+		if l != labelNone && match(l) {
+			dst = append(dst, entry{Start: t.starts[i], End: t.ends[i]})
+		}
+	}
+	return dst
+}
+
+// appendKey is the IPv6 counterpart of v4Table.appendKey.
+func (t *v6Table) appendKey(dst []entry6, key uint32, match func(label uint32) bool) []entry6 {
+	if t.postings != nil {
+		for _, i := range posting(t.postings, key) {
+			if int(i) < len(t.starts) {
+				dst = append(dst, entry6{Start: t.starts[i], End: t.ends[i]})
+			}
+		}
+		return dst
+	}
+	for i, l := range t.labels {
+		if match(l) {
+			dst = append(dst, entry6{Start: t.starts[i], End: t.ends[i]})
+		}
+	}
+	return dst
+}
+
+// keySet returns the entries of the given tables listed under any of keys.
+func (v *v4DB) keySet(keys []uint32, t4 []*v4Table, t6 []*v6Table) ipSet {
+	var r4 []entry
+	var r6 []entry6
+	for _, k := range keys {
+		eq := func(l uint32) bool { return l == k }
+		for _, t := range t4 {
+			r4 = t.appendKey(r4, k, eq)
+		}
+		for _, t := range t6 {
+			r6 = t.appendKey(r6, k, eq)
+		}
+	}
+	return newIPSet(r4, r6)
+}
+
+// providerKeySet returns the ranges of the given providers, including those
+// overlapped by more specific providers.
+func (v *v4DB) providerKeySet(keys []uint32) ipSet {
+	var r4 []entry
+	var r6 []entry6
+	for _, k := range keys {
+		eq := func(l uint32) bool { return l == k }
+		in := func(off uint32) bool { return slices.Contains(v.providerSet(off), k) }
+		r4 = v.provider.appendKey(r4, k, eq)
+		r4 = v.providerMulti.appendKey(r4, k, in)
+		r6 = v.provider6.appendKey(r6, k, eq)
+		r6 = v.providerMulti6.appendKey(r6, k, in)
+	}
+	return newIPSet(r4, r6)
+}
+
+// ProviderPrefixes returns all CIDRs of a provider (key or alias), IPv4
+// first, then IPv6, like ProviderIPs without formatting them.
+func (db *DB) ProviderPrefixes(provider string) ([]netip.Prefix, error) {
+	if db == nil || db.v4 == nil {
+		return nil, ErrInvalidDB
+	}
+	idx, ok := db.v4.providerByKey[provider]
+	if !ok {
+		return nil, ErrUnknownVendor
+	}
+	out := []netip.Prefix{}
+	err := db.v4.providerKeySet([]uint32{idx}).prefixes(func(p netip.Prefix) error {
+		out = append(out, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WriteProviderCIDRs writes all CIDRs of a provider (key or alias) to w, one
+// per line, IPv4 first, then IPv6.
+func (db *DB) WriteProviderCIDRs(w io.Writer, provider string) error {
+	if db == nil || db.v4 == nil {
+		return ErrInvalidDB
+	}
+	idx, ok := db.v4.providerByKey[provider]
+	if !ok {
+		return ErrUnknownVendor
+	}
+	bw := bufio.NewWriter(w)
+	var line []byte
+	err := db.v4.providerKeySet([]uint32{idx}).prefixes(func(p netip.Prefix) error {
+		line = append(p.AppendTo(line[:0]), '\n')
+		_, err := bw.Write(line)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
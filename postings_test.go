@@ -0,0 +1,72 @@
+package iplist
+
+import (
+	"bytes"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPostings(t *testing.T) {
+	b := NewBuilder()
+	_ = b.AddCountry(netip.MustParsePrefix("10.0.0.0/8"), "CN")
+	_ = b.AddCountry(netip.MustParsePrefix("12.0.0.0/8"), "CN")
+	_ = b.AddCountry(netip.MustParsePrefix("11.0.0.0/8"), "US")
+	_ = b.AddProvider(netip.MustParsePrefix("10.0.0.0/12"), "chinatelecom", "", ProviderKindISP)
+	_ = b.AddProvider(netip.MustParsePrefix("10.0.1.0/24"), "aliyun", "", ProviderKindCloud)
+	_ = b.AddProvider(netip.MustParsePrefix("2001:db8::/32"), "chinatelecom", "", ProviderKindISP)
+	_ = b.AddProvider(netip.MustParsePrefix("2001:db8:1::/48"), "aliyun", "", ProviderKindCloud)
+	db := buildTestDB(t, b)
+	if db.v4.country.postings == nil || db.v4.providerMulti.postings == nil {
+		t.Fatal("no postings written")
+	}
+
+	query := func() []string {
+		var out []string
+		for _, key := range []string{"chinatelecom", "aliyun"} {
+			ps, err := db.ProviderPrefixes(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := db.WriteProviderCIDRs(&buf, key); err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range ps {
+				out = append(out, key+" "+p.String())
+			}
+			if lines, _, _ := db.ProviderIPs(key); strings.Join(lines, "\n")+"\n" != buf.String() {
+				t.Errorf("WriteProviderCIDRs(%s) = %q", key, buf.String())
+			}
+		}
+		cn, _ := db.CountryIPs("CN")
+		return append(out, cn...)
+	}
+	want := []string{"chinatelecom 10.0.0.0/12", "chinatelecom 2001:db8::/32", "aliyun 10.0.1.0/24", "aliyun 2001:db8:1::/48", "10.0.0.0/8", "12.0.0.0/8"}
+	if got := query(); !slices.Equal(got, want) {
+		t.Errorf("with postings: %v", got)
+	}
+	// WithoutChecksum does not validate postings; malformed ones must not
+	// crash reverse queries.
+	saved := db.v4.country.postings
+	for _, p := range [][]uint32{{9}, {2, 0, 5, 1}, {2, 3, 1, 2, 0, 0}, {1, 0, 1, 1000}} {
+		db.v4.country.postings = p
+		_, _ = db.CountryIPs("CN")
+	}
+	db.v4.country.postings = saved
+
+	// Files without postings fall back to a scan.
+	for _, tab := range []*v4Table{&db.v4.country, &db.v4.provider, &db.v4.providerMulti} {
+		tab.postings = nil
+	}
+	for _, tab := range []*v6Table{&db.v4.country6, &db.v4.provider6, &db.v4.providerMulti6} {
+		tab.postings = nil
+	}
+	if got := query(); !slices.Equal(got, want) {
+		t.Errorf("without postings: %v", got)
+	}
+	if _, err := db.ProviderPrefixes("nope"); err != ErrUnknownVendor {
+		t.Errorf("ProviderPrefixes(nope): %v", err)
+	}
+}
@@ -1,9 +1,5 @@
 package iplist
 
-import (
-	"sort"
-)
-
 func (v *v4DB) providerCIDRsAny(provider string) ([]string, ProviderKind, error) {
 	idx, ok := v.providerByKey[provider]
 	if !ok {
@@ -20,31 +16,11 @@ func (v *v4DB) providerCIDRs(provider string, want ProviderKind) ([]string, erro
 		return nil, ErrUnknownVendor
 	}
 	_, _, kind := v.providerLabel(idx)
-	if want != ProviderKindUnknown && kind != want {
+	if want != ProviderKindUnknown && !kind.Has(want) {
 		return nil, ErrUnknownVendor
 	}
 
-	// Collect ranges for this provider label.
-	rangesIdx := make([]int, 0, 1024)
-	for i, label := range v.provider.labels {
-		if label == idx {
-			rangesIdx = append(rangesIdx, i)
-		}
-	}
-	if len(rangesIdx) == 0 {
-		return []string{}, nil
-	}
-	sort.Slice(rangesIdx, func(i, j int) bool { return v.provider.starts[rangesIdx[i]] < v.provider.starts[rangesIdx[j]] })
-
-	out := make([]string, 0, len(rangesIdx))
-	for _, i := range rangesIdx {
-		ps, err := rangeToCIDRs(v.provider.starts[i], v.provider.ends[i])
-		if err != nil {
-			return nil, err
-		}
-		for _, p := range ps {
-			out = append(out, p.String())
-		}
-	}
-	return out, nil
+	// Every range of this provider, including those where a more specific
+	// provider overlaps it.
+	return v.providerKeySet([]uint32{idx}).cidrs()
 }
@@ -0,0 +1,224 @@
+package iplist
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Provider ranges may overlap, e.g. a cloud block announced inside a carrier
+// allocation. Build splits them into disjoint segments:
+//
+//   - tableProvider labels every segment with its most specific provider, the
+//     one whose range is smallest (lowest label on ties). Readers that know
+//     nothing else keep working with a single provider per address.
+//   - tableProviderMulti holds only the segments covered by more than one
+//     provider. Its labels are offsets into secProviderSets, which stores
+//     each provider list as a count followed by the provider labels, most
+//     specific first.
+
+// providerSetWriter interns provider lists into the secProviderSets layout.
+type providerSetWriter struct {
+	data []uint32
+	idx  map[string]uint32
+}
+
+func (w *providerSetWriter) id(labels []uint32) uint32 {
+	var sb strings.Builder
+	for _, l := range labels {
+		sb.WriteString(strconv.FormatUint(uint64(l), 10))
+		sb.WriteByte(',')
+	}
+	k := sb.String()
+	if off, ok := w.idx[k]; ok {
+		return off
+	}
+	if w.idx == nil {
+		w.idx = make(map[string]uint32)
+	}
+	off := uint32(len(w.data))
+	w.data = append(w.data, uint32(len(labels)))
+	w.data = append(w.data, labels...)
+	w.idx[k] = off
+	return off
+}
+
+// mergeByLabel merges overlapping and adjacent ranges of the same label.
+// The result is sorted by start.
+func mergeByLabel(in []entry) []entry {
+	s := slices.Clone(in)
+	sort.Slice(s, func(i, j int) bool {
+		if s[i].Label != s[j].Label {
+			return s[i].Label < s[j].Label
+		}
+		return s[i].Start < s[j].Start
+	})
+	out := s[:0]
+	for _, e := range s {
+		if n := len(out); n > 0 && out[n-1].Label == e.Label && uint64(e.Start) <= uint64(out[n-1].End)+1 {
+			out[n-1].End = max(out[n-1].End, e.End)
+			continue
+		}
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Start < out[j].Start })
+	return out
+}
+
+// segmentProviders splits provider ranges into the tableProvider and
+// tableProviderMulti entries described above.
+func segmentProviders(in []entry, sets *providerSetWriter) (primary, multi []entry) {
+	in = mergeByLabel(in)
+	var labels []uint32
+	sweep(in, func(start, end uint32, active []int) {
+		slices.SortFunc(active, func(a, b int) int {
+			sa, sb := in[a].End-in[a].Start, in[b].End-in[b].Start
+			if sa != sb {
+				if sa < sb {
+					return -1
+				}
+				return 1
+			}
+			return int(in[a].Label) - int(in[b].Label)
+		})
+		primary = appendSegment(primary, start, end, in[active[0]].Label)
+		if len(active) > 1 {
+			labels = labels[:0]
+			for _, a := range active {
+				labels = append(labels, in[a].Label)
+			}
+			multi = appendSegment(multi, start, end, sets.id(labels))
+		}
+	})
+	return primary, multi
+}
+
+func appendSegment(out []entry, start, end, label uint32) []entry {
+	if n := len(out); n > 0 && out[n-1].Label == label && out[n-1].End+1 == start {
+		out[n-1].End = end
+		return out
+	}
+	return append(out, entry{Start: start, End: end, Label: label})
+}
+
+// mergeByLabel6 is the IPv6 counterpart of mergeByLabel.
+func mergeByLabel6(in []entry6) []entry6 {
+	s := slices.Clone(in)
+	sort.Slice(s, func(i, j int) bool {
+		if s[i].Label != s[j].Label {
+			return s[i].Label < s[j].Label
+		}
+		return s[i].Start.less(s[j].Start)
+	})
+	out := s[:0]
+	for _, e := range s {
+		if n := len(out); n > 0 && out[n-1].Label == e.Label &&
+			(!out[n-1].End.less(e.Start) || (!out[n-1].End.isMax() && out[n-1].End.addOne() == e.Start)) {
+			if out[n-1].End.less(e.End) {
+				out[n-1].End = e.End
+			}
+			continue
+		}
+		out = append(out, e)
+	}
+	sortEntries6(out)
+	return out
+}
+
+// segmentProviders6 is the IPv6 counterpart of segmentProviders.
+func segmentProviders6(in []entry6, sets *providerSetWriter) (primary, multi []entry6) {
+	in = mergeByLabel6(in)
+	var labels []uint32
+	sweep6(in, func(start, end u128, active []int) {
+		slices.SortFunc(active, func(a, b int) int {
+			sa, sb := in[a].End.sub(in[a].Start), in[b].End.sub(in[b].Start)
+			if sa != sb {
+				if sa.less(sb) {
+					return -1
+				}
+				return 1
+			}
+			return int(in[a].Label) - int(in[b].Label)
+		})
+		primary = appendSegment6(primary, start, end, in[active[0]].Label)
+		if len(active) > 1 {
+			labels = labels[:0]
+			for _, a := range active {
+				labels = append(labels, in[a].Label)
+			}
+			multi = appendSegment6(multi, start, end, sets.id(labels))
+		}
+	})
+	return primary, multi
+}
+
+func appendSegment6(out []entry6, start, end u128, label uint32) []entry6 {
+	if n := len(out); n > 0 && out[n-1].Label == label && !out[n-1].End.isMax() && out[n-1].End.addOne() == start {
+		out[n-1].End = end
+		return out
+	}
+	return append(out, entry6{Start: start, End: end, Label: label})
+}
+
+// providerIDs4 returns the providers of ip, most specific first. The slice
+// points into the database and must not be modified.
+func (v *v4DB) providerIDs4(ip uint32, primary uint32) []uint32 {
+	if len(v.providerMulti.starts) > 0 {
+		if off, ok := v.providerMulti.lookup(ip); ok {
+			return v.providerSet(off)
+		}
+	}
+	return v.providerSelf[primary : primary+1]
+}
+
+// providerIDs6 is the IPv6 counterpart of providerIDs4.
+func (v *v4DB) providerIDs6(ip u128, primary uint32) []uint32 {
+	if len(v.providerMulti6.starts) > 0 {
+		if off, ok := v.providerMulti6.lookup(ip); ok {
+			return v.providerSet(off)
+		}
+	}
+	return v.providerSelf[primary : primary+1]
+}
+
+func (v *v4DB) providerSet(off uint32) []uint32 {
+	n := v.providerSets[off]
+	return v.providerSets[off+1 : off+1+n : off+1+n]
+}
+
+// initProviderSets validates secProviderSets against the multi tables and
+// builds providerSelf, the identity list single-provider lookups return
+// slices of.
+func (v *v4DB) initProviderSets() error {
+	v.providerSelf = make([]uint32, len(v.providerLabels))
+	for i := range v.providerSelf {
+		v.providerSelf[i] = uint32(i)
+	}
+	check := func(off uint32) bool {
+		if uint64(off) >= uint64(len(v.providerSets)) {
+			return false
+		}
+		n := uint64(v.providerSets[off])
+		if uint64(off)+1+n > uint64(len(v.providerSets)) {
+			return false
+		}
+		for _, l := range v.providerSets[off+1 : uint64(off)+1+n] {
+			if l >= uint32(len(v.providerLabels)) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, off := range v.providerMulti.labels {
+		if !check(off) {
+			return ErrInvalidDB
+		}
+	}
+	for _, off := range v.providerMulti6.labels {
+		if !check(off) {
+			return ErrInvalidDB
+		}
+	}
+	return nil
+}
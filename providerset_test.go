@@ -0,0 +1,52 @@
+package iplist
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestProviderOverlap(t *testing.T) {
+	b := NewBuilder()
+	_ = b.AddProvider(netip.MustParsePrefix("10.0.0.0/8"), "carrier", "", ProviderKindISP)
+	_ = b.AddProvider(netip.MustParsePrefix("10.1.0.0/16"), "cloud", "", ProviderKindCloud)
+	_ = b.AddProvider(netip.MustParsePrefix("10.1.2.0/24"), "cdn", "", ProviderKindCDN)
+	_ = b.AddProvider(netip.MustParsePrefix("2001:db8::/32"), "carrier", "", ProviderKindISP)
+	_ = b.AddProvider(netip.MustParsePrefix("2001:db8:1::/48"), "cloud", "", ProviderKindCloud)
+	db := buildTestDB(t, b)
+
+	key := func(id uint32) string { k, _, _, _ := db.ProviderByID(id); return k }
+	tests := []struct {
+		ip   string
+		want []string
+	}{
+		{"10.0.0.1", []string{"carrier"}},
+		{"10.1.0.1", []string{"cloud", "carrier"}},
+		{"10.1.2.1", []string{"cdn", "cloud", "carrier"}},
+		{"2001:db8::1", []string{"carrier"}},
+		{"2001:db8:1::1", []string{"cloud", "carrier"}},
+	}
+	for _, tt := range tests {
+		ids, _, _ := db.LookupIDs(tt.ip)
+		var got []string
+		for _, id := range ids.ProviderIDs {
+			got = append(got, key(id))
+		}
+		if !slices.Equal(got, tt.want) || key(ids.ProviderID) != tt.want[0] {
+			t.Errorf("%s: providers %v (primary %s), want %v", tt.ip, got, key(ids.ProviderID), tt.want)
+		}
+		if res, _, _ := db.Lookup(tt.ip); res.ProviderKey != tt.want[0] {
+			t.Errorf("%s: provider %q", tt.ip, res.ProviderKey)
+		}
+	}
+
+	for key, want := range map[string][]string{
+		"carrier": {"10.0.0.0/8", "2001:db8::/32"},
+		"cloud":   {"10.1.0.0/16", "2001:db8:1::/48"},
+		"cdn":     {"10.1.2.0/24"},
+	} {
+		if got, _, _ := db.ProviderIPs(key); !slices.Equal(got, want) {
+			t.Errorf("ProviderIPs(%s) = %v, want %v", key, got, want)
+		}
+	}
+}
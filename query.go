@@ -0,0 +1,174 @@
+package iplist
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Select evaluates a set expression and returns the addresses it selects as
+// minimal CIDRs, IPv4 first, then IPv6.
+//
+// A term selects the addresses of one label:
+//
+//	country:CN       a country (ISO 3166-1 code)
+//	cn:440000        a CN province, city or district with its subregions
+//	provider:aliyun  a provider, by key or alias
+//	kind:cloud       every provider of a kind (see ParseProviderKind)
+//
+// Terms combine with - (difference), & (intersection) and | (union), in
+// decreasing order of precedence, and with parentheses:
+//
+//	country:CN & provider:chinatelecom - kind:cloud
+//	cn:440000 - (provider:aliyun | provider:tencent)
+//
+// A - only acts as an operator at the start of a word, so it needs a space
+// (or a parenthesis) before it. Unknown labels return an error wrapping
+// ErrUnknownCountry, ErrUnknownCity or ErrUnknownVendor.
+func (db *DB) Select(expr string) ([]string, error) {
+	if db == nil || db.v4 == nil {
+		return nil, ErrInvalidDB
+	}
+	p := &queryParser{db: db, toks: tokenizeQuery(expr)}
+	s, err := p.union()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("iplist: query: unexpected %q", p.toks[p.pos])
+	}
+	return s.cidrs()
+}
+
+func tokenizeQuery(expr string) []string {
+	var toks []string
+	for i := 0; i < len(expr); {
+		switch c := expr[i]; {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case strings.IndexByte("()&|-", c) >= 0:
+			toks = append(toks, expr[i:i+1])
+			i++
+		default:
+			j := i
+			for j < len(expr) && strings.IndexByte(" \t\n()&|", expr[j]) < 0 {
+				j++
+			}
+			toks = append(toks, expr[i:j])
+			i = j
+		}
+	}
+	return toks
+}
+
+// queryParser evaluates the expression while parsing it:
+//
+//	union := inter { "|" inter }
+//	inter := diff { "&" diff }
+//	diff  := atom { "-" atom }
+//	atom  := term | "(" union ")"
+type queryParser struct {
+	db   *DB
+	toks []string
+	pos  int
+}
+
+func (p *queryParser) accept(tok string) bool {
+	if p.pos < len(p.toks) && p.toks[p.pos] == tok {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) union() (ipSet, error) {
+	s, err := p.inter()
+	for err == nil && p.accept("|") {
+		var o ipSet
+		if o, err = p.inter(); err == nil {
+			s = s.union(o)
+		}
+	}
+	return s, err
+}
+
+func (p *queryParser) inter() (ipSet, error) {
+	s, err := p.diff()
+	for err == nil && p.accept("&") {
+		var o ipSet
+		if o, err = p.diff(); err == nil {
+			s = s.intersect(o)
+		}
+	}
+	return s, err
+}
+
+func (p *queryParser) diff() (ipSet, error) {
+	s, err := p.atom()
+	for err == nil && p.accept("-") {
+		var o ipSet
+		if o, err = p.atom(); err == nil {
+			s = s.minus(o)
+		}
+	}
+	return s, err
+}
+
+func (p *queryParser) atom() (ipSet, error) {
+	if p.pos >= len(p.toks) {
+		return ipSet{}, fmt.Errorf("iplist: query: unexpected end of expression")
+	}
+	if p.accept("(") {
+		s, err := p.union()
+		if err != nil {
+			return ipSet{}, err
+		}
+		if !p.accept(")") {
+			return ipSet{}, fmt.Errorf("iplist: query: missing )")
+		}
+		return s, nil
+	}
+	tok := p.toks[p.pos]
+	p.pos++
+	return p.db.v4.querySet(tok)
+}
+
+// querySet returns the addresses of a single term such as country:CN.
+func (v *v4DB) querySet(term string) (ipSet, error) {
+	kind, value, ok := strings.Cut(term, ":")
+	if !ok || value == "" {
+		return ipSet{}, fmt.Errorf("iplist: query: invalid term %q", term)
+	}
+	switch kind {
+	case "country":
+		idx, ok := v.countryByCode(strings.ToUpper(value))
+		if !ok {
+			return ipSet{}, fmt.Errorf("%w: %s", ErrUnknownCountry, value)
+		}
+		return v.countrySet(idx), nil
+	case "cn":
+		idx, ok := v.resolveCNCode(value)
+		if !ok {
+			return ipSet{}, fmt.Errorf("%w: %s", ErrUnknownCity, value)
+		}
+		return v.cnRegionSet(idx, true), nil
+	case "provider":
+		idx, ok := v.providerByKey[value]
+		if !ok {
+			return ipSet{}, fmt.Errorf("%w: %s", ErrUnknownVendor, value)
+		}
+		return v.providerKeySet([]uint32{idx}), nil
+	case "kind":
+		want, err := ParseProviderKind(value)
+		if err != nil {
+			return ipSet{}, err
+		}
+		var keys []uint32
+		for i, pl := range v.providerLabels {
+			if ProviderKind(pl.Kind).Has(want) {
+				keys = append(keys, uint32(i))
+			}
+		}
+		return v.providerKeySet(keys), nil
+	}
+	return ipSet{}, fmt.Errorf("iplist: query: unknown term type %q", kind)
+}
@@ -0,0 +1,47 @@
+package iplist
+
+import (
+	"errors"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	b := NewBuilder()
+	_ = b.AddCountry(netip.MustParsePrefix("10.0.0.0/8"), "CN")
+	_ = b.AddCountry(netip.MustParsePrefix("2001:db8::/32"), "CN")
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.0.0.0/16"), "440000")
+	_ = b.AddProvider(netip.MustParsePrefix("10.0.0.0/12"), "chinatelecom", "", ProviderKindISP)
+	_ = b.AddProvider(netip.MustParsePrefix("10.0.1.0/24"), "aliyun", "", ProviderKindCloud)
+	_ = b.AddProvider(netip.MustParsePrefix("10.0.4.0/24"), "tencent", "", ProviderKindCloud)
+	_ = b.AddProvider(netip.MustParsePrefix("2001:db8:1::/48"), "aliyun", "", ProviderKindCloud)
+	db := buildTestDB(t, b)
+
+	for expr, want := range map[string][]string{
+		"country:CN & provider:chinatelecom - kind:cloud":  {"10.0.0.0/24", "10.0.2.0/23", "10.0.5.0/24", "10.0.6.0/23", "10.0.8.0/21", "10.0.16.0/20", "10.0.32.0/19", "10.0.64.0/18", "10.0.128.0/17", "10.1.0.0/16", "10.2.0.0/15", "10.4.0.0/14", "10.8.0.0/13"},
+		"cn:440000 - (provider:aliyun | provider:tencent)": {"10.0.0.0/24", "10.0.2.0/23", "10.0.5.0/24", "10.0.6.0/23", "10.0.8.0/21", "10.0.16.0/20", "10.0.32.0/19", "10.0.64.0/18", "10.0.128.0/17"},
+		"kind:cloud & country:CN":                          {"10.0.1.0/24", "10.0.4.0/24", "2001:db8:1::/48"},
+		"provider:aliyun|provider:tencent":                 {"10.0.1.0/24", "10.0.4.0/24", "2001:db8:1::/48"},
+		"country:CN - (country:CN)":                        {},
+	} {
+		got, err := db.Select(expr)
+		if err != nil || !slices.Equal(got, want) {
+			t.Errorf("Select(%q) = %v %v", expr, got, err)
+		}
+	}
+	for expr, want := range map[string]error{
+		"country:ZZ":           ErrUnknownCountry,
+		"cn:990000 | kind:cdn": ErrUnknownCity,
+		"provider:nope":        ErrUnknownVendor,
+	} {
+		if _, err := db.Select(expr); !errors.Is(err, want) {
+			t.Errorf("Select(%q): %v", expr, err)
+		}
+	}
+	for _, expr := range []string{"", "country:CN &", "(country:CN", "country:CN)", "CN", "kind:nope"} {
+		if _, err := db.Select(expr); err == nil {
+			t.Errorf("Select(%q) succeeded", expr)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package iplist
+
+// RangeLabel describes the label of a table entry yielded by DB.Ranges.
+type RangeLabel struct {
+	ID   uint32 // label ID, as in ResultIDs
+	Code string // country, subdivision or CN admin code, provider key or category label
+	Name string
+	Kind ProviderKind // providers only
+}
+
+// Ranges calls fn for every entry of a table in address order, IPv4 first,
+// until fn returns false. category is one of country, subdivision,
+// cn_province, cn_city, cn_district and provider, or the name of a custom
+// category. The provider table holds the most specific provider of each
+// range, as returned by Lookup. Unknown categories return
+// ErrUnknownCategory.
+func (db *DB) Ranges(category string, fn func(r Range, label RangeLabel) bool) error {
+	if db == nil || db.v4 == nil {
+		return ErrInvalidDB
+	}
+	t4, t6, label, ok := db.v4.rangeTable(category)
+	if !ok {
+		return ErrUnknownCategory
+	}
+	for i, l := range t4.labels {
+		if l == labelNone && t4.dense {
+			continue
+		}
+		if !fn(range4(t4.starts[i], t4.ends[i]), label(l)) {
+			return nil
+		}
+	}
+	for i, l := range t6.labels {
+		if !fn(Range{Start: t6.starts[i].addr(), End: t6.ends[i].addr()}, label(l)) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// rangeTable returns the tables of a category for Ranges, along with the
+// decoder of their labels.
+func (v *v4DB) rangeTable(category string) (*v4Table, *v6Table, func(uint32) RangeLabel, bool) {
+	codeName := func(decode func(uint32) (string, string)) func(uint32) RangeLabel {
+		return func(id uint32) RangeLabel {
+			code, name := decode(id)
+			return RangeLabel{ID: id, Code: code, Name: name}
+		}
+	}
+	switch category {
+	case "country":
+		return &v.country, &v.country6, codeName(v.countryLabel), true
+	case "subdivision":
+		return &v.subdiv, &v.subdiv6, codeName(v.subdivLabel), true
+	case "cn_province":
+		return &v.cnProv, &v.cnProv6, codeName(v.cnLabel), true
+	case "cn_city":
+		return &v.cnCity, &v.cnCity6, codeName(v.cnLabel), true
+	case "cn_district":
+		return &v.cnDist, &v.cnDist6, codeName(v.cnLabel), true
+	case "provider":
+		return &v.provider, &v.provider6, func(id uint32) RangeLabel {
+			key, name, kind := v.providerLabel(id)
+			return RangeLabel{ID: id, Code: key, Name: name, Kind: kind}
+		}, true
+	}
+	i, ok := v.categoryByName[category]
+	if !ok {
+		return nil, nil, nil, false
+	}
+	c := &v.categories[i]
+	return &c.t4, &c.t6, func(id uint32) RangeLabel {
+		return RangeLabel{ID: id, Code: c.label(v, id)}
+	}, true
+}
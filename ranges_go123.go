@@ -0,0 +1,23 @@
+//go:build go1.23
+
+package iplist
+
+import "iter"
+
+// RangesSeq is the iterator form of Ranges:
+//
+//	seq, err := db.RangesSeq("country")
+//	for r, label := range seq {
+//		...
+//	}
+func (db *DB) RangesSeq(category string) (iter.Seq2[Range, RangeLabel], error) {
+	if db == nil || db.v4 == nil {
+		return nil, ErrInvalidDB
+	}
+	if _, _, _, ok := db.v4.rangeTable(category); !ok {
+		return nil, ErrUnknownCategory
+	}
+	return func(yield func(Range, RangeLabel) bool) {
+		_ = db.Ranges(category, yield)
+	}, nil
+}
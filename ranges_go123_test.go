@@ -0,0 +1,30 @@
+//go:build go1.23
+
+package iplist
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestRangesSeq(t *testing.T) {
+	b := NewBuilder()
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.0.0.0/16"), "440000")
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.2.0.0/16"), "110000")
+	db := buildTestDB(t, b)
+
+	seq, err := db.RangesSeq("cn_province")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var codes []string
+	for _, l := range seq {
+		codes = append(codes, l.Code)
+	}
+	if len(codes) != 2 || codes[0] != "440000" || codes[1] != "110000" {
+		t.Errorf("RangesSeq(cn_province) = %v", codes)
+	}
+	if _, err := db.RangesSeq("nope"); err != ErrUnknownCategory {
+		t.Errorf("RangesSeq(nope): %v", err)
+	}
+}
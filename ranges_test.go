@@ -0,0 +1,39 @@
+package iplist
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestRanges(t *testing.T) {
+	b := NewBuilder()
+	_ = b.AddCountry(netip.MustParsePrefix("10.0.0.0/8"), "CN")
+	_ = b.AddCountry(netip.MustParsePrefix("11.0.0.0/8"), "US")
+	_ = b.AddCountry(netip.MustParsePrefix("2001:db8::/32"), "CN")
+	_ = b.AddProvider(netip.MustParsePrefix("10.0.0.0/12"), "chinatelecom", "", ProviderKindISP)
+	_ = b.AddProvider(netip.MustParsePrefix("10.0.1.0/24"), "aliyun", "", ProviderKindCloud)
+	db := buildTestDB(t, b)
+
+	var got []string
+	err := db.Ranges("country", func(r Range, l RangeLabel) bool {
+		got = append(got, r.Start.String()+"-"+r.End.String()+" "+l.Code)
+		return true
+	})
+	want := []string{"10.0.0.0-10.255.255.255 CN", "11.0.0.0-11.255.255.255 US", "2001:db8::-2001:db8:ffff:ffff:ffff:ffff:ffff:ffff CN"}
+	if err != nil || !slices.Equal(got, want) {
+		t.Errorf("Ranges(country) = %v %v", got, err)
+	}
+
+	got = got[:0]
+	_ = db.Ranges("provider", func(r Range, l RangeLabel) bool {
+		got = append(got, r.Prefixes()[0].String()+" "+l.Code+" "+l.Kind.String())
+		return len(got) < 2
+	})
+	if want := []string{"10.0.0.0/24 chinatelecom isp", "10.0.1.0/24 aliyun cloud"}; !slices.Equal(got, want) {
+		t.Errorf("Ranges(provider) = %v", got)
+	}
+	if err := db.Ranges("nope", func(Range, RangeLabel) bool { return true }); err != ErrUnknownCategory {
+		t.Errorf("Ranges(nope): %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package iplist
+
+import "strings"
+
+// CountryIPs returns all CIDRs of a country, given by its ISO 3166-1 code
+// (e.g. "CN"), IPv4 first, then IPv6.
+func (db *DB) CountryIPs(code string) ([]string, error) {
+	if db == nil || db.v4 == nil {
+		return nil, ErrInvalidDB
+	}
+	v := db.v4
+	idx, ok := v.countryByCode(strings.ToUpper(code))
+	if !ok {
+		return nil, ErrUnknownCountry
+	}
+	return v.countrySet(idx).cidrs()
+}
+
+// CNRegionIPs returns all CIDRs of a CN province, city or district, given by
+// its admin code, IPv4 first, then IPv6. Historical codes are resolved as by
+// ResolveCNCode. With subregions, a province also covers the ranges listed
+// for its cities and districts, and a city those of its districts.
+func (db *DB) CNRegionIPs(code string, subregions bool) ([]string, error) {
+	if db == nil || db.v4 == nil {
+		return nil, ErrInvalidDB
+	}
+	idx, ok := db.v4.resolveCNCode(code)
+	if !ok {
+		return nil, ErrUnknownCity
+	}
+	return db.v4.cnRegionSet(idx, subregions).cidrs()
+}
+
+func (v *v4DB) countrySet(idx uint32) ipSet {
+	return v.keySet([]uint32{idx}, []*v4Table{&v.country}, []*v6Table{&v.country6})
+}
+
+func (v *v4DB) cnRegionSet(idx uint32, subregions bool) ipSet {
+	keys := []uint32{idx}
+	if subregions {
+		for l := range v.cnLabels {
+			if v.cnCityOf[l] == idx || v.cnProvOf[l] == idx {
+				keys = append(keys, uint32(l))
+			}
+		}
+	}
+	return v.keySet(keys,
+		[]*v4Table{&v.cnProv, &v.cnCity, &v.cnDist},
+		[]*v6Table{&v.cnProv6, &v.cnCity6, &v.cnDist6})
+}
+
+func (v *v4DB) countryByCode(code string) (uint32, bool) {
+	for i := range v.countryLabels {
+		if c, _ := v.countryLabel(uint32(i)); c == code {
+			return uint32(i), true
+		}
+	}
+	return IDNone, false
+}
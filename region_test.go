@@ -0,0 +1,44 @@
+package iplist
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestRegionIPs(t *testing.T) {
+	b := NewBuilder()
+	_ = b.AddCountry(netip.MustParsePrefix("10.0.0.0/9"), "CN")
+	_ = b.AddCountry(netip.MustParsePrefix("10.128.0.0/9"), "CN")
+	_ = b.AddCountry(netip.MustParsePrefix("2001:db8::/32"), "CN")
+	_ = b.AddCountry(netip.MustParsePrefix("11.0.0.0/8"), "US")
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.0.0.0/16"), "440000")
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.0.0.0/24"), "440300")
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.1.0.0/24"), "440100")
+	_ = b.AddCNRegion(netip.MustParsePrefix("10.1.1.0/24"), "440305")
+	db := buildTestDB(t, b)
+
+	if got, err := db.CountryIPs("cn"); err != nil || !slices.Equal(got, []string{"10.0.0.0/8", "2001:db8::/32"}) {
+		t.Errorf("CountryIPs(cn) = %v %v", got, err)
+	}
+	if _, err := db.CountryIPs("ZZ"); err != ErrUnknownCountry {
+		t.Errorf("CountryIPs(ZZ): %v", err)
+	}
+	for _, tc := range []struct {
+		code string
+		sub  bool
+		want []string
+	}{
+		{"440000", false, []string{"10.0.0.0/16"}},
+		{"440000", true, []string{"10.0.0.0/16", "10.1.0.0/23"}},
+		{"440300", false, []string{"10.0.0.0/24"}},
+		{"440300", true, []string{"10.0.0.0/24", "10.1.1.0/24"}},
+	} {
+		if got, err := db.CNRegionIPs(tc.code, tc.sub); err != nil || !slices.Equal(got, tc.want) {
+			t.Errorf("CNRegionIPs(%s, %v) = %v %v", tc.code, tc.sub, got, err)
+		}
+	}
+	if _, err := db.CNRegionIPs("990000", false); err != ErrUnknownCity {
+		t.Errorf("CNRegionIPs(990000): %v", err)
+	}
+}
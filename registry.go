@@ -0,0 +1,219 @@
+package iplist
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ProviderRegistryFile is the provider registry LoadFS reads from the root
+// of a data directory, see LoadProviderRegistry.
+const ProviderRegistryFile = "providers.tsv"
+
+// DefaultProviderRegistry is the maintained registry Build reads unless
+// WithProviderRegistry is given, relative to the parent of the data
+// directory. Like DefaultCNMigrations it lives outside the data directory
+// because that is regenerated from scratch.
+const DefaultProviderRegistry = "src/providers.tsv"
+
+// WithProviderRegistry makes Build read the provider registry in path
+// instead of DefaultProviderRegistry; the file must exist. An empty path
+// reads none. NewBuilder ignores it: call LoadProviderRegistry on the
+// Builder instead.
+func WithProviderRegistry(path string) BuildOption {
+	return func(c *buildConfig) { c.providerRegistry, c.providerRegistrySet = path, true }
+}
+
+// ProviderInfo describes a provider as recorded in the registry.
+type ProviderInfo struct {
+	Key     string // e.g. tencent
+	Name    string // display name, e.g. 腾讯云
+	NameEN  string // English display name, e.g. Tencent Cloud
+	Kind    ProviderKind
+	Aliases []string // alternative keys accepted by ProviderIPs etc.
+	ASNs    []uint32 // autonomous systems announcing the provider's ranges
+}
+
+// providerRecord is the part of ProviderInfo that is not in the provider
+// label table. secProviderInfo holds a JSON array of them in label order.
+type providerRecord struct {
+	Key     string   `json:"key"`
+	NameEN  string   `json:"name_en,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+	ASNs    []uint32 `json:"asns,omitempty"`
+}
+
+// SetProviderInfo registers the name, kind, aliases and ASNs of a provider.
+// Registered values take precedence over the built-in ones; a name or kind
+// passed to AddProvider takes precedence over both. Registering a key again
+// replaces the earlier entry.
+func (b *Builder) SetProviderInfo(info ProviderInfo) error {
+	if info.Key == "" {
+		return fmt.Errorf("iplist: empty provider key")
+	}
+	for _, a := range info.Aliases {
+		if a == "" || a == info.Key {
+			return fmt.Errorf("iplist: provider %q: invalid alias %q", info.Key, a)
+		}
+	}
+	if b.registry == nil {
+		b.registry = make(map[string]ProviderInfo)
+	}
+	info.Aliases = slices.Clone(info.Aliases)
+	info.ASNs = slices.Clone(info.ASNs)
+	b.registry[info.Key] = info
+	return nil
+}
+
+// LoadProviderRegistry reads provider registry entries as tab-separated
+// lines
+//
+//	key<TAB>kind<TAB>name<TAB>name_en<TAB>aliases<TAB>asns
+//
+// kind is parsed by ParseProviderKind (e.g. "cloud,cdn"), aliases and asns are comma-separated (ASNs with or
+// without the AS prefix). Trailing fields may be omitted and empty fields
+// keep the built-in value. Empty lines and lines starting with # are ignored.
+func (b *Builder) LoadProviderRegistry(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimRight(s.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Split(line, "\t")
+		if len(f) > 6 {
+			return fmt.Errorf("iplist: provider registry line %d: too many fields", n)
+		}
+		f = append(f, make([]string, 6-len(f))...)
+		for i := range f {
+			f[i] = strings.TrimSpace(f[i])
+		}
+		info := ProviderInfo{Key: f[0], Name: f[2], NameEN: f[3], Aliases: splitList(f[4])}
+		var err error
+		if info.Kind, err = ParseProviderKind(f[1]); err != nil {
+			return fmt.Errorf("iplist: provider registry line %d: %w", n, err)
+		}
+		for _, a := range splitList(f[5]) {
+			asn, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(a), "AS"), 10, 32)
+			if err != nil {
+				return fmt.Errorf("iplist: provider registry line %d: invalid ASN %q", n, a)
+			}
+			info.ASNs = append(info.ASNs, uint32(asn))
+		}
+		if err := b.SetProviderInfo(info); err != nil {
+			return fmt.Errorf("iplist: provider registry line %d: %w", n, err)
+		}
+	}
+	return s.Err()
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// resolveProvider returns the name, kind and registry record written for key:
+// AddProvider arguments first, then the registry, then the built-in tables.
+func (b *Builder) resolveProvider(key string) (name string, kind ProviderKind, rec providerRecord) {
+	info := b.providerInfo[b.providers.idx[key]]
+	reg := b.registry[key]
+	name, kind = info.name, info.kind
+	if name == "" {
+		name = reg.Name
+	}
+	if name == "" {
+		name = b.providerNames[key]
+	}
+	if name == "" {
+		name = key
+	}
+	if kind == ProviderKindUnknown {
+		kind = reg.Kind
+	}
+	if kind == ProviderKindUnknown {
+		kind = b.providerKinds[key]
+	}
+	if kind == ProviderKindUnknown {
+		kind = ProviderKindISP
+	}
+	return name, kind, providerRecord{Key: key, NameEN: reg.NameEN, Aliases: reg.Aliases, ASNs: reg.ASNs}
+}
+
+// encodeProviderRecords checks that aliases are unambiguous and returns the
+// secProviderInfo blob, or nil when no provider has registry data.
+func encodeProviderRecords(recs []providerRecord) ([]byte, error) {
+	owner := make(map[string]string, len(recs))
+	for _, r := range recs {
+		owner[r.Key] = r.Key
+	}
+	empty := true
+	for _, r := range recs {
+		for _, a := range r.Aliases {
+			if o, ok := owner[a]; ok && o != r.Key {
+				return nil, fmt.Errorf("iplist: provider alias %q of %q is already used by %q", a, r.Key, o)
+			}
+			owner[a] = r.Key
+		}
+		if r.NameEN != "" || len(r.Aliases) > 0 || len(r.ASNs) > 0 {
+			empty = false
+		}
+	}
+	if empty {
+		return nil, nil
+	}
+	return json.Marshal(recs)
+}
+
+// initProviderRecords parses secProviderInfo and registers the aliases in
+// providerByKey.
+func (v *v4DB) initProviderRecords() error {
+	if len(v.providerInfo) == 0 {
+		return nil
+	}
+	var recs []providerRecord
+	if err := json.Unmarshal(v.providerInfo, &recs); err != nil || len(recs) != len(v.providerLabels) {
+		return ErrInvalidDB
+	}
+	for i, r := range recs {
+		if r.Key != v.str(v.providerLabels[i].Key) {
+			return ErrInvalidDB
+		}
+		for _, a := range r.Aliases {
+			if _, ok := v.providerByKey[a]; !ok {
+				v.providerByKey[a] = uint32(i)
+				v.providerKindByKey[a] = ProviderKind(v.providerLabels[i].Kind)
+			}
+		}
+	}
+	v.providerRecords = recs
+	return nil
+}
+
+// ProviderInfo returns the registry entry of a provider by key or alias.
+func (db *DB) ProviderInfo(key string) (ProviderInfo, bool) {
+	if db == nil || db.v4 == nil {
+		return ProviderInfo{}, false
+	}
+	idx, ok := db.v4.providerByKey[key]
+	if !ok {
+		return ProviderInfo{}, false
+	}
+	info := ProviderInfo{}
+	info.Key, info.Name, info.Kind = db.v4.providerLabel(idx)
+	if int(idx) < len(db.v4.providerRecords) {
+		r := db.v4.providerRecords[idx]
+		info.NameEN = r.NameEN
+		info.Aliases = slices.Clone(r.Aliases)
+		info.ASNs = slices.Clone(r.ASNs)
+	}
+	return info, true
+}
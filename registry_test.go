@@ -0,0 +1,91 @@
+package iplist
+
+import (
+	"bytes"
+	"net/netip"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestProviderRegistry(t *testing.T) {
+	fsys := fstest.MapFS{
+		"isp/tencent.txt": {Data: []byte("1.1.1.0/24\n")},
+		"isp/acme.txt":    {Data: []byte("2.2.2.0/24\n")},
+		"isp/cernet.txt":  {Data: []byte("3.3.3.0/24\n")},
+		ProviderRegistryFile: {Data: []byte("# key\tkind\tname\tname_en\taliases\tasns\n" +
+			"tencent\tcloud\t腾讯云\tTencent Cloud\ttencentcloud,qcloud\tAS45090,132203\n" +
+			"acme\tcloud\tAcme 云\n" +
+			"unused\tisp\tUnused\t\tnone\n")},
+	}
+	b := NewBuilder()
+	if err := b.LoadFS(fsys); err != nil {
+		t.Fatal(err)
+	}
+	db := buildTestDB(t, b)
+
+	cidrs, kind, err := db.ProviderIPs("tencentcloud")
+	if err != nil || kind != ProviderKindCloud || len(cidrs) != 1 || cidrs[0] != "1.1.1.0/24" {
+		t.Fatalf("ProviderIPs(tencentcloud) = %v %v %v", cidrs, kind, err)
+	}
+	info, ok := db.ProviderInfo("qcloud")
+	if !ok || info.Key != "tencent" || info.NameEN != "Tencent Cloud" || len(info.ASNs) != 2 || info.ASNs[1] != 132203 {
+		t.Errorf("ProviderInfo(qcloud) = %+v", info)
+	}
+	if res, _, _ := db.Lookup("2.2.2.2"); res.ProviderName != "Acme 云" || res.ProviderKind != ProviderKindCloud {
+		t.Errorf("acme: %q %v", res.ProviderName, res.ProviderKind)
+	}
+	// Not in the registry: built-in name and kind.
+	if res, _, _ := db.Lookup("3.3.3.3"); res.ProviderName != "中国教育网" || res.ProviderKind != ProviderKindEducation {
+		t.Errorf("cernet: %q %v", res.ProviderName, res.ProviderKind)
+	}
+	if _, ok := db.ProviderInfo("none"); ok {
+		t.Error("alias of a provider without ranges resolved")
+	}
+
+	c := NewBuilder()
+	_ = c.AddProvider(netip.MustParsePrefix("1.1.1.0/24"), "a", "", ProviderKindUnknown)
+	_ = c.AddProvider(netip.MustParsePrefix("2.2.2.0/24"), "b", "", ProviderKindUnknown)
+	_ = c.SetProviderInfo(ProviderInfo{Key: "a", Aliases: []string{"b"}})
+	if _, err := c.WriteTo(&bytes.Buffer{}); err == nil {
+		t.Error("alias shadowing a key accepted")
+	}
+}
+
+// TestBuildProviderRegistry checks how Build finds the registry and that the
+// data directory's own registry takes precedence.
+func TestBuildProviderRegistry(t *testing.T) {
+	root := writeDataDir(t, map[string]string{
+		"data/isp/acme.txt":            "1.1.1.0/24\n",
+		"data/isp/beta.txt":            "2.2.2.0/24\n",
+		DefaultProviderRegistry:        "acme\tcloud\tAcme\nbeta\tcloud\tBeta\n",
+		"data/" + ProviderRegistryFile: "beta\tcdn\tBeta CDN\n",
+		"other.tsv":                    "acme\thosting\tAcme Hosting\n",
+	})
+	dataDir := filepath.Join(root, "data")
+	tests := []struct {
+		name       string
+		opts       []BuildOption
+		acme, beta string
+	}{
+		{"default", nil, "Acme", "Beta CDN"},
+		{"explicit", []BuildOption{WithProviderRegistry(filepath.Join(root, "other.tsv"))}, "Acme Hosting", "Beta CDN"},
+		{"none", []BuildOption{WithProviderRegistry("")}, "acme", "Beta CDN"},
+	}
+	for _, tt := range tests {
+		out := filepath.Join(t.TempDir(), "iplist.db")
+		if err := Build(dataDir, out, tt.opts...); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		db, err := Open(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, _, _ := db.Lookup("1.1.1.1")
+		b, _, _ := db.Lookup("2.2.2.2")
+		if a.ProviderName != tt.acme || b.ProviderName != tt.beta {
+			t.Errorf("%s: names %q, %q", tt.name, a.ProviderName, b.ProviderName)
+		}
+		db.Close()
+	}
+}
@@ -0,0 +1,119 @@
+package iplist
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader serves a database file and reloads it when the file changes.
+//
+// It polls the file's size, modification time and identity. Replace the file
+// by writing the new version next to it and renaming it over the old one: the
+// current DB is a shared mapping of the old file, so rewriting that file in
+// place changes the pages under in-flight lookups, which may then read torn
+// data or fault with SIGBUS. In-place rewrites are only safe with
+// WithHeapCopy, which keeps no mapping.
+//
+// A new file that fails to open (for example because it fails its checksum)
+// is skipped and the previous DB keeps serving; LastError reports the
+// failure. Old DBs are closed once in-flight lookups are done.
+//
+// DBs are opened with WithHeapStrings in addition to the given options, so
+// Result strings stay valid after a reload.
+type Reloader struct {
+	*AtomicDB
+
+	path string
+	opts []OpenOption
+
+	mu      sync.Mutex
+	stat    os.FileInfo
+	lastErr error
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewReloader opens path and, if interval > 0, polls it for changes every
+// interval. The initial open must succeed.
+func NewReloader(path string, interval time.Duration, opts ...OpenOption) (*Reloader, error) {
+	r := &Reloader{
+		AtomicDB: &AtomicDB{},
+		path:     path,
+		opts:     append(append([]OpenOption(nil), opts...), WithHeapStrings()),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		close(r.done)
+		return r, nil
+	}
+	go r.poll(interval)
+	return r, nil
+}
+
+// Reload reopens the file unconditionally and swaps it in on success.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reloadLocked()
+}
+
+func (r *Reloader) reloadLocked() error {
+	st, err := os.Stat(r.path)
+	if err == nil {
+		var db *DB
+		db, err = Open(r.path, r.opts...)
+		if err == nil {
+			r.stat = st
+			r.AtomicDB.Swap(db)
+		}
+	}
+	r.lastErr = err
+	return err
+}
+
+// LastError returns the error of the most recent reload attempt, or nil.
+func (r *Reloader) LastError() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastErr
+}
+
+// Close stops polling and retires the current DB. It is safe to call more
+// than once and from several goroutines.
+func (r *Reloader) Close() error {
+	r.closeOnce.Do(func() { close(r.stop) })
+	<-r.done
+	return r.AtomicDB.Close()
+}
+
+func (r *Reloader) poll(interval time.Duration) {
+	defer close(r.done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-t.C:
+		}
+		r.mu.Lock()
+		if st, err := os.Stat(r.path); err != nil {
+			r.lastErr = err
+		} else if r.changed(st) {
+			_ = r.reloadLocked()
+		}
+		r.mu.Unlock()
+	}
+}
+
+func (r *Reloader) changed(st os.FileInfo) bool {
+	prev := r.stat
+	return prev == nil || !os.SameFile(prev, st) || prev.Size() != st.Size() || !prev.ModTime().Equal(st.ModTime())
+}
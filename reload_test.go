@@ -0,0 +1,122 @@
+package iplist
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitCountry polls r until 1.0.0.1 resolves to want.
+func waitCountry(t *testing.T, r *Reloader, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		res, _, err := r.Lookup("1.0.0.1")
+		if err == nil && res.CountryCode == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("country %q, %v; want %s", res.CountryCode, err, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestReloaderRename(t *testing.T) {
+	path := countryTestDB(t, "CN")
+	r, err := NewReloader(path, 5*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	waitCountry(t, r, "CN")
+
+	if err := os.Rename(countryTestDB(t, "US"), path); err != nil {
+		t.Fatal(err)
+	}
+	waitCountry(t, r, "US")
+
+	// A broken replacement is skipped and the previous DB keeps serving.
+	bad, err := os.ReadFile(countryTestDB(t, "JP"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad[len(bad)-1] ^= 0xff
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, bad, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for r.LastError() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("corrupted file was not reported")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	waitCountry(t, r, "US")
+}
+
+func TestReloaderModTime(t *testing.T) {
+	path := countryTestDB(t, "CN")
+	// The file is rewritten in place, so keep it out of the mapping.
+	r, err := NewReloader(path, 5*time.Millisecond, WithHeapCopy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	st, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Same size and inode: only the modification time tells them apart.
+	data, err := os.ReadFile(countryTestDB(t, "US"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(data)) != st.Size() {
+		t.Fatalf("replacement is %d bytes, want %d", len(data), st.Size())
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := st.ModTime().Add(time.Hour)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	waitCountry(t, r, "US")
+}
+
+func TestReloaderClose(t *testing.T) {
+	r, err := NewReloader(countryTestDB(t, "CN"), time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = r.Close()
+		}()
+	}
+	wg.Wait()
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.Lookup("1.0.0.1"); err != ErrInvalidDB {
+		t.Errorf("Lookup after Close: %v, want ErrInvalidDB", err)
+	}
+
+	// Without polling there is no goroutine to stop.
+	r, err = NewReloader(countryTestDB(t, "CN"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = r.Close()
+	_ = r.Close()
+}
@@ -0,0 +1,452 @@
+package iplist
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/binary"
+	"slices"
+	"unsafe"
+)
+
+// Format version 3 replaces the fixed v2 section header with a section
+// directory:
+//
+//	header (64 bytes)
+//	  0..4    magic "IPL4"
+//	  4..6    version (3)
+//	  6..8    header flags
+//	  8..16   build time (unix seconds)
+//	  16..20  directory offset
+//	  20..24  directory entry count
+//	  24..32  file size and checksum (see verify.go)
+//	sections, each 8-byte aligned
+//	directory: dirCount x sectionEntry
+//
+// Readers skip sections they do not understand unless the section carries
+// sectionFlagRequired. New tables and metadata can therefore be added without
+// breaking older readers.
+const version3 = 3
+
+// sectionEntry is one record of the v3 section directory.
+// Off/Len are absolute byte offsets/lengths in the file.
+type sectionEntry struct {
+	Type  uint16
+	Table uint16 // table id for per-table sections, 0 otherwise
+	Flags uint32
+	Off   uint32
+	Len   uint32
+}
+
+const sectionEntrySize = 16
+
+// sectionFlagRequired marks a section that a reader must understand.
+// Unknown sections without this flag are skipped.
+const sectionFlagRequired uint32 = 1 << 0
+
+// sectionFlagDense is set on secStarts4 when the table covers the whole
+// IPv4 space without gaps (see v4Table.dense). Files written before tables
+// could go without a bucket index carry it on secBucketLo instead.
+const sectionFlagDense uint32 = 1 << 1
+
+// Section types.
+const (
+	secStrings        uint16 = 1
+	secCountryLabels  uint16 = 2
+	secCNLabels       uint16 = 3
+	secProviderLabels uint16 = 4
+	secMetadata       uint16 = 5  // JSON-encoded Metadata
+	secSignature      uint16 = 6  // ed25519 signature, see verify.go
+	secSubdivLabels   uint16 = 7  // ISO 3166-2 subdivision labels (label2)
+	secSpecialLabels  uint16 = 8  // special set names (u32 string ids), see special.go
+	secCNMigrations   uint16 = 9  // cnMigration records sorted by Old, see cnmigrate.go
+	secProviderInfo   uint16 = 10 // JSON-encoded providerRecord per provider label
+	secProviderSets   uint16 = 11 // provider lists of tableProviderMulti, see providerset.go
+	secCategories     uint16 = 12 // categoryRecord per custom category, see category.go
+	secCategoryLabels uint16 = 13 // label names (u32 string ids) of custom table Table
+
+	// Per-table columns; Table holds the table id.
+	secStarts4 uint16 = 16
+	secEnds4   uint16 = 17
+	secLabels4 uint16 = 18
+	secStarts6 uint16 = 19
+	secEnds6   uint16 = 20
+	secLabels6 uint16 = 21
+
+	// Precomputed bucket index of an IPv4 table (1<<bucketBits(n) u32
+	// each), see v4Table.bucketLo/bucketHi. Tables with fewer than 256
+	// entries have none.
+	secBucketLo uint16 = 22
+	secBucketHi uint16 = 23
+
+	// Per-label entry indices of a table, see postings.go.
+	secPostings4 uint16 = 24
+	secPostings6 uint16 = 25
+)
+
+// Table ids.
+const (
+	tableCountry  uint16 = 1
+	tableCNProv   uint16 = 2
+	tableCNCity   uint16 = 3
+	tableProvider uint16 = 4
+	tableSubdiv   uint16 = 5
+	tableSpecial  uint16 = 6 // labels are set bitmasks
+	tableCNDist   uint16 = 7
+
+	tableProviderMulti uint16 = 8 // labels are secProviderSets offsets
+
+	// Custom category tables use tableCustomBase and up, see category.go.
+)
+
+func (v *v4DB) table4(id uint16) *v4Table {
+	switch id {
+	case tableCountry:
+		return &v.country
+	case tableCNProv:
+		return &v.cnProv
+	case tableCNCity:
+		return &v.cnCity
+	case tableProvider:
+		return &v.provider
+	case tableCNDist:
+		return &v.cnDist
+	case tableSubdiv:
+		return &v.subdiv
+	case tableSpecial:
+		return &v.special
+	case tableProviderMulti:
+		return &v.providerMulti
+	}
+	if c := v.customTable(id); c != nil {
+		return &c.t4
+	}
+	return nil
+}
+
+func (v *v4DB) table6(id uint16) *v6Table {
+	switch id {
+	case tableCountry:
+		return &v.country6
+	case tableCNProv:
+		return &v.cnProv6
+	case tableCNCity:
+		return &v.cnCity6
+	case tableProvider:
+		return &v.provider6
+	case tableCNDist:
+		return &v.cnDist6
+	case tableSubdiv:
+		return &v.subdiv6
+	case tableSpecial:
+		return &v.special6
+	case tableProviderMulti:
+		return &v.providerMulti6
+	}
+	if c := v.customTable(id); c != nil {
+		return &c.t6
+	}
+	return nil
+}
+
+// --- writer ---
+
+type sectionWriter struct {
+	buf *bytes.Buffer
+	dir []sectionEntry
+}
+
+func newSectionWriter() *sectionWriter {
+	buf := &bytes.Buffer{}
+	buf.Grow(16 * 1024)
+	// Reserve header.
+	buf.Write(make([]byte, headerSize))
+	return &sectionWriter{buf: buf}
+}
+
+// add appends a section. data is a []byte or a slice of fixed-size values,
+// encoded little-endian. Empty sections are omitted.
+func (w *sectionWriter) add(typ, table uint16, flags uint32, data any) error {
+	if pad := (-w.buf.Len()) & 7; pad != 0 {
+		w.buf.Write(make([]byte, pad))
+	}
+	off := w.buf.Len()
+	if b, ok := data.([]byte); ok {
+		w.buf.Write(b)
+	} else if err := binary.Write(w.buf, binary.LittleEndian, data); err != nil {
+		return err
+	}
+	n := w.buf.Len() - off
+	if n == 0 {
+		return nil
+	}
+	w.dir = append(w.dir, sectionEntry{Type: typ, Table: table, Flags: flags, Off: uint32(off), Len: uint32(n)})
+	return nil
+}
+
+// addTable4 writes an IPv4 table. Postings are written when pk is set.
+func (w *sectionWriter) addTable4(table uint16, entries []entry, pk *postingKeys) error {
+	starts, ends, labels := splitEntries(entries)
+	t := v4Table{starts: starts, ends: ends, labels: labels}
+	t.detectDense()
+	var flags uint32
+	if t.dense {
+		flags |= sectionFlagDense
+	}
+	if err := w.add(secStarts4, table, flags, starts); err != nil {
+		return err
+	}
+	if err := w.add(secEnds4, table, 0, ends); err != nil {
+		return err
+	}
+	if err := w.add(secLabels4, table, 0, labels); err != nil {
+		return err
+	}
+	if pk != nil && len(labels) > 0 {
+		if err := w.add(secPostings4, table, 0, buildPostings(labels, pk)); err != nil {
+			return err
+		}
+	}
+	t.buildBuckets()
+	if t.bucketLo == nil {
+		return nil
+	}
+	if err := w.add(secBucketLo, table, 0, t.bucketLo); err != nil {
+		return err
+	}
+	return w.add(secBucketHi, table, 0, t.bucketHi)
+}
+
+func (w *sectionWriter) addTable6(table uint16, entries []entry6, pk *postingKeys) error {
+	starts, ends, labels := splitEntries6(entries)
+	if err := w.add(secStarts6, table, 0, starts); err != nil {
+		return err
+	}
+	if err := w.add(secEnds6, table, 0, ends); err != nil {
+		return err
+	}
+	if err := w.add(secLabels6, table, 0, labels); err != nil {
+		return err
+	}
+	if pk != nil && len(labels) > 0 {
+		return w.add(secPostings6, table, 0, buildPostings(labels, pk))
+	}
+	return nil
+}
+
+// finish writes the directory and the header and returns the checksummed
+// file bytes. If key is set, the file is signed with it.
+func (w *sectionWriter) finish(buildTime int64, key ed25519.PrivateKey) ([]byte, error) {
+	var sig *sectionEntry
+	if key != nil {
+		if err := w.add(secSignature, 0, 0, make([]byte, ed25519.SignatureSize)); err != nil {
+			return nil, err
+		}
+		sig = &w.dir[len(w.dir)-1]
+	}
+	if pad := (-w.buf.Len()) & 7; pad != 0 {
+		w.buf.Write(make([]byte, pad))
+	}
+	dirOff := uint32(w.buf.Len())
+	if err := binary.Write(w.buf, binary.LittleEndian, w.dir); err != nil {
+		return nil, err
+	}
+	out := w.buf.Bytes()
+	copy(out[0:4], []byte(magicV4))
+	binary.LittleEndian.PutUint16(out[4:6], version3)
+	binary.LittleEndian.PutUint16(out[6:8], 0)
+	binary.LittleEndian.PutUint64(out[8:16], uint64(buildTime))
+	binary.LittleEndian.PutUint32(out[16:20], dirOff)
+	binary.LittleEndian.PutUint32(out[20:24], uint32(len(w.dir)))
+	sealFile(out, sig, key)
+	return out, nil
+}
+
+// --- reader ---
+
+func readSectionDir(b []byte) ([]sectionEntry, error) {
+	dirOff := int(binary.LittleEndian.Uint32(b[16:20]))
+	dirCount := int(binary.LittleEndian.Uint32(b[20:24]))
+	if dirOff < headerSize || dirCount < 0 || dirOff+dirCount*sectionEntrySize > len(b) {
+		return nil, ErrInvalidDB
+	}
+	dir := make([]sectionEntry, dirCount)
+	for i := range dir {
+		e := b[dirOff+i*sectionEntrySize:]
+		dir[i] = sectionEntry{
+			Type:  binary.LittleEndian.Uint16(e[0:2]),
+			Table: binary.LittleEndian.Uint16(e[2:4]),
+			Flags: binary.LittleEndian.Uint32(e[4:8]),
+			Off:   binary.LittleEndian.Uint32(e[8:12]),
+			Len:   binary.LittleEndian.Uint32(e[12:16]),
+		}
+		if int(dir[i].Off) < headerSize || int(dir[i].Off)+int(dir[i].Len) > len(b) {
+			return nil, ErrInvalidDB
+		}
+	}
+	return dir, nil
+}
+
+func parseV4v3(b []byte, cfg *openConfig) (*v4DB, error) {
+	if err := checkFileSize(b, !cfg.noChecksum); err != nil {
+		return nil, err
+	}
+	dir, err := readSectionDir(b)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyFile(b, dir, !cfg.noChecksum, cfg.publicKey); err != nil {
+		return nil, err
+	}
+	v := &v4DB{buildTime: int64(binary.LittleEndian.Uint64(b[8:16]))}
+	haveStrings := false
+	for _, s := range dir {
+		off, n := int(s.Off), int(s.Len)
+		switch s.Type {
+		case secStrings:
+			v.stringsData, v.stringsStart, v.stringsEnd, err = parseStringsTable(b[off : off+n])
+			if cfg.heapStrings {
+				v.stringsData = bytes.Clone(v.stringsData)
+			}
+			haveStrings = true
+		case secCountryLabels:
+			v.countryLabels, err = sliceSection[label2](b, s, 4)
+		case secCNLabels:
+			v.cnLabels, err = sliceSection[label2](b, s, 4)
+		case secProviderLabels:
+			v.providerLabels, err = sliceSection[providerLabel](b, s, 4)
+		case secSubdivLabels:
+			v.subdivLabels, err = sliceSection[label2](b, s, 4)
+		case secSpecialLabels:
+			v.specialLabels, err = sliceSection[uint32](b, s, 4)
+		case secCNMigrations:
+			v.cnMigrations, err = sliceSection[cnMigration](b, s, 4)
+		case secMetadata:
+			v.metadata = b[off : off+n]
+		case secProviderInfo:
+			v.providerInfo = b[off : off+n]
+		case secProviderSets:
+			v.providerSets, err = sliceSection[uint32](b, s, 4)
+			if cfg.heapStrings {
+				v.providerSets = slices.Clone(v.providerSets)
+			}
+		case secCategories:
+			v.categoryRecords, err = sliceSection[categoryRecord](b, s, 4)
+		case secCategoryLabels:
+			c := v.customTable(s.Table)
+			if c == nil {
+				continue
+			}
+			c.labels, err = sliceSection[uint32](b, s, 4)
+		case secSignature:
+			// Checked by verifyFile.
+		case secStarts4, secEnds4, secLabels4:
+			t := v.table4(s.Table)
+			if t == nil {
+				continue
+			}
+			col, e := sliceSection[uint32](b, s, 4)
+			switch s.Type {
+			case secStarts4:
+				t.starts = col
+				t.dense = t.dense || s.Flags&sectionFlagDense != 0
+			case secEnds4:
+				t.ends = col
+			default:
+				t.labels = col
+			}
+			err = e
+		case secBucketLo, secBucketHi:
+			t := v.table4(s.Table)
+			if t == nil {
+				continue
+			}
+			col, e := sliceSection[uint32](b, s, 4)
+			if s.Type == secBucketLo {
+				t.bucketLo = col
+				t.dense = t.dense || s.Flags&sectionFlagDense != 0
+			} else {
+				t.bucketHi = col
+			}
+			err = e
+		case secStarts6, secEnds6:
+			t := v.table6(s.Table)
+			if t == nil {
+				continue
+			}
+			col, e := sliceSection[u128](b, s, 8)
+			if s.Type == secStarts6 {
+				t.starts = col
+			} else {
+				t.ends = col
+			}
+			err = e
+		case secLabels6:
+			t := v.table6(s.Table)
+			if t == nil {
+				continue
+			}
+			t.labels, err = sliceSection[uint32](b, s, 4)
+		case secPostings4:
+			t := v.table4(s.Table)
+			if t == nil {
+				continue
+			}
+			t.postings, err = sliceSection[uint32](b, s, 4)
+		case secPostings6:
+			t := v.table6(s.Table)
+			if t == nil {
+				continue
+			}
+			t.postings, err = sliceSection[uint32](b, s, 4)
+		default:
+			if s.Flags&sectionFlagRequired != 0 {
+				return nil, ErrInvalidDB
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !haveStrings {
+		return nil, ErrInvalidDB
+	}
+	for _, t := range [...]*v6Table{&v.country6, &v.cnProv6, &v.cnCity6, &v.cnDist6, &v.provider6, &v.subdiv6, &v.special6, &v.providerMulti6} {
+		if len(t.starts) != len(t.ends) || len(t.starts) != len(t.labels) {
+			return nil, ErrInvalidDB
+		}
+	}
+	if err := v.finish(); err != nil {
+		return nil, err
+	}
+	// Validating the postings reads all of them; WithoutChecksum keeps Open
+	// independent of the file size and relies on the bounds checks in posting
+	// and appendKey.
+	if !cfg.noChecksum {
+		if err := v.checkPostings(); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// sliceSection maps a fixed-width section in place. word is the width of the
+// little-endian words T is made of; on big-endian hosts they are swapped first.
+func sliceSection[T any](b []byte, s sectionEntry, word int) ([]T, error) {
+	sz := int(unsafe.Sizeof(*new(T)))
+	if int(s.Len)%sz != 0 || int(s.Off)%word != 0 {
+		return nil, ErrInvalidDB
+	}
+	count := int(s.Len) / sz
+	if !nativeLittleEndian {
+		var err error
+		if word == 8 {
+			err = swapU64Words(b, int(s.Off), int(s.Len)/8)
+		} else {
+			err = swapU32Words(b, int(s.Off), int(s.Len)/4)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sliceFixed[T](b, int(s.Off), count)
+}
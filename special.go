@@ -0,0 +1,202 @@
+package iplist
+
+import (
+	"net/netip"
+	"slices"
+	"sort"
+)
+
+// Special sets are named IP lists that are kept apart from the country table,
+// e.g. "china" (data/special/china.txt: CN ranges plus a hand-maintained
+// whitelist). A range may belong to several sets, so the special table is
+// stored as disjoint ranges labelled with a bitmask: bit i is set i of the
+// secSpecialLabels section.
+
+// SpecialChina is the mainland-China set built from data/special/china.txt.
+const SpecialChina = "china"
+
+// maxSpecialSets is the number of bits in a special-table label.
+const maxSpecialSets = 32
+
+// InSpecial reports whether r.Special contains name.
+func (r *Result) InSpecial(name string) bool {
+	return slices.Contains(r.Special, name)
+}
+
+// SpecialSets returns the names of the special sets in the database.
+// Set i corresponds to bit 1<<i of ResultIDs.SpecialMask.
+func (db *DB) SpecialSets() []string {
+	if db == nil || db.v4 == nil {
+		return nil
+	}
+	return slices.Clone(db.v4.specialSets)
+}
+
+// SpecialMask returns the ResultIDs.SpecialMask bit of the named set.
+func (db *DB) SpecialMask(name string) (uint32, bool) {
+	if db == nil || db.v4 == nil {
+		return 0, false
+	}
+	i := slices.Index(db.v4.specialSets, name)
+	if i < 0 {
+		return 0, false
+	}
+	return 1 << i, true
+}
+
+// InSpecialSet reports whether addr is in the named special set.
+// It only searches the special table.
+func (db *DB) InSpecialSet(addr netip.Addr, name string) (bool, error) {
+	bit, ok := db.SpecialMask(name)
+	if !ok {
+		if db == nil || db.v4 == nil {
+			return false, ErrInvalidDB
+		}
+		return false, ErrUnknownSpecial
+	}
+	if !addr.IsValid() {
+		return false, ErrUnsupportedIP
+	}
+	return db.v4.lookupSpecial(addr)&bit != 0, nil
+}
+
+func (v *v4DB) lookupSpecial(addr netip.Addr) uint32 {
+	if addr.Is4() || addr.Is4In6() {
+		ip4 := addr.As4()
+		mask, _ := v.special.lookup(uint32(ip4[0])<<24 | uint32(ip4[1])<<16 | uint32(ip4[2])<<8 | uint32(ip4[3]))
+		return mask
+	}
+	mask, _ := v.special6.lookup(u128FromAddr(addr))
+	return mask
+}
+
+// specialNames returns the shared, read-only list of set names for mask.
+func (v *v4DB) specialNames(mask uint32) []string {
+	return v.specialByMask[mask]
+}
+
+// initSpecial resolves set names and precomputes the name list of every mask
+// that occurs in the special table, so lookups do not allocate.
+func (v *v4DB) initSpecial() error {
+	if len(v.specialLabels) > maxSpecialSets {
+		return ErrInvalidDB
+	}
+	v.specialSets = make([]string, len(v.specialLabels))
+	for i, s := range v.specialLabels {
+		v.specialSets[i] = v.str(s)
+		if v.specialSets[i] == "" {
+			return ErrInvalidDB
+		}
+	}
+	v.specialByMask = make(map[uint32][]string)
+	add := func(mask uint32) {
+		if _, ok := v.specialByMask[mask]; ok {
+			return
+		}
+		var names []string
+		for i, name := range v.specialSets {
+			if mask&(1<<i) != 0 {
+				names = append(names, name)
+			}
+		}
+		v.specialByMask[mask] = names
+	}
+	for _, m := range v.special.labels {
+		add(m)
+	}
+	for _, m := range v.special6.labels {
+		add(m)
+	}
+	return nil
+}
+
+// segmentMasks turns per-set ranges (Label = set index, merged within each set)
+// into disjoint ranges labelled with the bitmask of the sets covering them.
+// Adjacent ranges with equal masks are joined.
+func segmentMasks(in []entry) []entry {
+	type event struct {
+		pos uint64
+		bit uint32
+		on  bool
+	}
+	events := make([]event, 0, 2*len(in))
+	for _, e := range in {
+		events = append(events, event{uint64(e.Start), 1 << e.Label, true}, event{uint64(e.End) + 1, 1 << e.Label, false})
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].pos < events[j].pos })
+
+	var out []entry
+	var mask uint32
+	for i := 0; i < len(events); {
+		pos := events[i].pos
+		for ; i < len(events) && events[i].pos == pos; i++ {
+			if events[i].on {
+				mask |= events[i].bit
+			} else {
+				mask &^= events[i].bit
+			}
+		}
+		if mask == 0 || i == len(events) {
+			continue
+		}
+		end := uint32(events[i].pos - 1)
+		if n := len(out); n > 0 && out[n-1].Label == mask && uint64(out[n-1].End)+1 == pos {
+			out[n-1].End = end
+			continue
+		}
+		out = append(out, entry{Start: uint32(pos), End: end, Label: mask})
+	}
+	return out
+}
+
+// segmentMasks6 is the IPv6 counterpart of segmentMasks.
+func segmentMasks6(in []entry6) []entry6 {
+	type event struct {
+		pos  u128
+		past bool // position is one past the last address
+		bit  uint32
+		on   bool
+	}
+	events := make([]event, 0, 2*len(in))
+	for _, e := range in {
+		events = append(events, event{pos: e.Start, bit: 1 << e.Label, on: true})
+		if e.End.isMax() {
+			events = append(events, event{past: true, bit: 1 << e.Label})
+		} else {
+			events = append(events, event{pos: e.End.addOne(), bit: 1 << e.Label})
+		}
+	}
+	less := func(a, b event) bool {
+		if a.past != b.past {
+			return b.past
+		}
+		return a.pos.less(b.pos)
+	}
+	sort.Slice(events, func(i, j int) bool { return less(events[i], events[j]) })
+
+	var out []entry6
+	var mask uint32
+	for i := 0; i < len(events); {
+		cur := events[i]
+		for ; i < len(events) && !less(cur, events[i]); i++ {
+			if events[i].on {
+				mask |= events[i].bit
+			} else {
+				mask &^= events[i].bit
+			}
+		}
+		if mask == 0 || i == len(events) || cur.past {
+			continue
+		}
+		end := hostMask(128)
+		if !events[i].past {
+			end = events[i].pos.subOne()
+		}
+		if n := len(out); n > 0 && out[n-1].Label == mask && !out[n-1].End.isMax() && out[n-1].End.addOne() == cur.pos {
+			out[n-1].End = end
+			continue
+		}
+		out = append(out, entry6{Start: cur.pos, End: end, Label: mask})
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+package iplist
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestBuilderSpecialSets(t *testing.T) {
+	b := NewBuilder()
+	add := func(p, set string) {
+		t.Helper()
+		if err := b.AddSpecial(netip.MustParsePrefix(p), set); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("10.0.0.0/16", "china")
+	add("10.0.128.0/17", "corp")
+	add("10.1.0.0/24", "corp")
+	add("2001:db8::/32", "china")
+	add("2001:db8:8000::/33", "corp")
+	// The country table does not affect special sets.
+	_ = b.AddCountry(netip.MustParsePrefix("10.0.0.0/24"), "HK")
+	db := buildTestDB(t, b)
+
+	if got := db.SpecialSets(); len(got) != 2 || got[0] != "china" || got[1] != "corp" {
+		t.Fatalf("sets %v", got)
+	}
+	china, _ := db.SpecialMask("china")
+	corp, _ := db.SpecialMask("corp")
+	tests := []struct {
+		ip   string
+		mask uint32
+	}{
+		{"10.0.0.1", china},
+		{"10.0.127.255", china},
+		{"10.0.128.0", china | corp},
+		{"10.0.255.255", china | corp},
+		{"10.1.0.1", corp},
+		{"10.2.0.1", 0},
+		{"2001:db8::1", china},
+		{"2001:db8:8000::1", china | corp},
+		{"2001:db9::", 0},
+	}
+	for _, tt := range tests {
+		ids, _, err := db.LookupIDs(tt.ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids.SpecialMask != tt.mask {
+			t.Errorf("%s: mask %b, want %b", tt.ip, ids.SpecialMask, tt.mask)
+		}
+		res, _, _ := db.Lookup(tt.ip)
+		if res.InSpecial("china") != (tt.mask&china != 0) || res.InSpecial("corp") != (tt.mask&corp != 0) {
+			t.Errorf("%s: special %v", tt.ip, res.Special)
+		}
+	}
+	if ok, err := db.InSpecialSet(netip.MustParseAddr("10.0.0.1"), SpecialChina); !ok || err != nil {
+		t.Errorf("InSpecialSet = %v, %v", ok, err)
+	}
+	if _, err := db.InSpecialSet(netip.MustParseAddr("10.0.0.1"), "nope"); err != ErrUnknownSpecial {
+		t.Errorf("err = %v", err)
+	}
+}
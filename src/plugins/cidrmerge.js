@@ -1,6 +1,11 @@
 const { merge } = require('fast-cidr-tools')
 
 const plugin = (file, _, cb) => {
+  // Only CIDR lists are merged; other files (e.g. subdivisions.tsv) pass through.
+  if (!file.path.endsWith('.txt')) {
+    return cb(null, file)
+  }
+
   let cidrs = file.contents.toString().split('\n')
 
   console.log(file.path)
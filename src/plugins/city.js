@@ -14,6 +14,7 @@ const plugin = (through2, file, cb) => {
   let bar = new ProgressBar(':bar :current/:total', { total: ipdb.meta.node_count })
 
   let result = []
+  let names = {}
   let ip = '0.0.0.0'
   while (true) {
     const info = ipdb.find(ip).data
@@ -22,6 +23,9 @@ const plugin = (through2, file, cb) => {
       if (!result[iso3166_2]) {
         result[iso3166_2] = []
       }
+      if (info.region_name && !names[iso3166_2]) {
+        names[iso3166_2] = info.region_name
+      }
       result[iso3166_2].push(`${info.range.from}/${info.bitmask}`)
     }
     bar.tick()
@@ -41,6 +45,15 @@ const plugin = (through2, file, cb) => {
     through2.push(temp)
   }
 
+  // Names for Go Build, read by LoadFS from data/country/subdivisions.tsv.
+  let lines = Object.keys(names).sort().map(code => `${code}\t${names[code]}`)
+  through2.push(new vinyl({
+    cwd: '/',
+    base: '/',
+    path: '/subdivisions.tsv',
+    contents: new Buffer.from(lines.join('\n') + '\n')
+  }))
+
   cb()
 }
 
@@ -21,6 +21,13 @@ const plugin = (through2, file, cb) => {
     const china_admin_code = info.china_admin_code
     if (china_admin_code?.length === 6) {
       let cac = china_admin_code
+      // 区县级
+      if (!cac.endsWith('00')) {
+        if (!result[cac]) {
+          result[cac] = []
+        }
+        result[cac].push(`${info.range.from}/${info.bitmask}`)
+      }
       {
         cac = `${cac.substr(0, 4)}00`
         if (!result[cac]) {
@@ -0,0 +1,143 @@
+package iplist
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+)
+
+// Integrity fields of the v3 header:
+//
+//	6..8    header flags (headerFlagChecksum)
+//	24..28  file size
+//	28..32  CRC-32C of the file
+//
+// The checksum and the signature are both computed over the whole file with
+// the CRC field and the signature section contents treated as zeros, so
+// neither covers the other. Every v3 writer sets headerFlagChecksum; a file
+// without it is rejected unless checksums are disabled, so clearing the flag
+// cannot switch verification off.
+const (
+	headerFlagChecksum uint16 = 1 << 0
+
+	hdrFileSizeOff = 24
+	hdrCRCOff      = 28
+)
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrChecksumMismatch is returned by Open when the file is truncated or
+// does not match its stored checksum.
+var ErrChecksumMismatch = errors.New("iplist: checksum mismatch")
+
+// SignatureError is returned by Open when a public key was given via
+// WithPublicKey and the file is unsigned or its signature does not verify.
+type SignatureError struct {
+	Reason string
+}
+
+func (e *SignatureError) Error() string {
+	return "iplist: signature verification failed: " + e.Reason
+}
+
+// writeDigest feeds b into h with the regions in skip replaced by zeros.
+// skip must be sorted and non-overlapping.
+func writeDigest(h hash.Hash, b []byte, skip [][2]int) {
+	var zeros [64]byte
+	pos := 0
+	for _, r := range skip {
+		h.Write(b[pos:r[0]])
+		for n := r[1] - r[0]; n > 0; {
+			k := min(n, len(zeros))
+			h.Write(zeros[:k])
+			n -= k
+		}
+		pos = r[1]
+	}
+	h.Write(b[pos:])
+}
+
+func integritySkips(sig *sectionEntry) [][2]int {
+	skip := [][2]int{{hdrCRCOff, hdrCRCOff + 4}}
+	if sig != nil {
+		skip = append(skip, [2]int{int(sig.Off), int(sig.Off + sig.Len)})
+	}
+	return skip
+}
+
+func fileCRC(b []byte, sig *sectionEntry) uint32 {
+	h := crc32.New(crc32c)
+	writeDigest(h, b, integritySkips(sig))
+	return h.Sum32()
+}
+
+func fileDigest(b []byte, sig *sectionEntry) []byte {
+	h := sha256.New()
+	writeDigest(h, b, integritySkips(sig))
+	return h.Sum(nil)
+}
+
+// sealFile signs (if key is set) and checksums a file produced by sectionWriter.
+// sig is the reserved signature section, or nil.
+func sealFile(b []byte, sig *sectionEntry, key ed25519.PrivateKey) {
+	flags := binary.LittleEndian.Uint16(b[6:8]) | headerFlagChecksum
+	binary.LittleEndian.PutUint16(b[6:8], flags)
+	binary.LittleEndian.PutUint32(b[hdrFileSizeOff:], uint32(len(b)))
+	binary.LittleEndian.PutUint32(b[hdrCRCOff:], 0)
+
+	var signature []byte
+	if sig != nil {
+		signature = ed25519.Sign(key, fileDigest(b, sig))
+	}
+	crc := fileCRC(b, sig)
+	if sig != nil {
+		copy(b[sig.Off:sig.Off+sig.Len], signature)
+	}
+	binary.LittleEndian.PutUint32(b[hdrCRCOff:], crc)
+}
+
+// checkFileSize compares the file size recorded in a v3 header with len(b).
+// It runs before the section directory is parsed so that a truncated file is
+// reported as such rather than as ErrInvalidDB.
+func checkFileSize(b []byte, checksum bool) error {
+	if !checksum {
+		return nil
+	}
+	if binary.LittleEndian.Uint16(b[6:8])&headerFlagChecksum == 0 {
+		return ErrInvalidDB
+	}
+	if int(binary.LittleEndian.Uint32(b[hdrFileSizeOff:])) != len(b) {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+// verifyFile checks the checksum of a v3 file and, if pub is set, its signature.
+func verifyFile(b []byte, dir []sectionEntry, checksum bool, pub ed25519.PublicKey) error {
+	var sig *sectionEntry
+	for i := range dir {
+		if dir[i].Type == secSignature {
+			sig = &dir[i]
+			break
+		}
+	}
+	if checksum && fileCRC(b, sig) != binary.LittleEndian.Uint32(b[hdrCRCOff:]) {
+		return ErrChecksumMismatch
+	}
+	if pub == nil {
+		return nil
+	}
+	if sig == nil {
+		return &SignatureError{Reason: "file is not signed"}
+	}
+	if sig.Len != ed25519.SignatureSize {
+		return &SignatureError{Reason: "malformed signature"}
+	}
+	if !ed25519.Verify(pub, fileDigest(b, sig), b[sig.Off:sig.Off+sig.Len]) {
+		return &SignatureError{Reason: "signature mismatch"}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package iplist
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func newVerifyBuilder(opts ...BuildOption) *Builder {
+	b := NewBuilder(opts...)
+	_ = b.AddCountry(netip.MustParsePrefix("1.0.0.0/24"), "CN")
+	_ = b.AddCountry(netip.MustParsePrefix("2001:db8::/32"), "US")
+	return b
+}
+
+func TestChecksum(t *testing.T) {
+	data := testDBBytes(t, newVerifyBuilder())
+	if db, err := OpenBytes(data); err != nil {
+		t.Fatal(err)
+	} else {
+		db.Close()
+	}
+
+	corrupt := bytes.Clone(data)
+	corrupt[len(corrupt)/2] ^= 0x01
+	if _, err := OpenBytes(corrupt); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("corrupted file: %v, want ErrChecksumMismatch", err)
+	}
+	for _, n := range []int{len(data) - 1, len(data) / 2, headerSize} {
+		if _, err := OpenBytes(data[:n]); !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("file truncated to %d bytes: %v, want ErrChecksumMismatch", n, err)
+		}
+	}
+
+	// Clearing the checksum flag must not turn verification off.
+	unflagged := bytes.Clone(corrupt)
+	unflagged[6] &^= byte(headerFlagChecksum)
+	if _, err := OpenBytes(unflagged); err != ErrInvalidDB {
+		t.Errorf("checksum flag cleared: %v, want ErrInvalidDB", err)
+	}
+}
+
+func TestSignature(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+	signed := testDBBytes(t, newVerifyBuilder(WithSigningKey(priv)))
+	unsigned := testDBBytes(t, newVerifyBuilder())
+
+	db, err := OpenBytes(signed, WithPublicKey(pub))
+	if err != nil {
+		t.Fatalf("valid signature: %v", err)
+	}
+	if res, _, _ := db.Lookup("1.0.0.1"); res.CountryCode != "CN" {
+		t.Errorf("signed file: country %q", res.CountryCode)
+	}
+	db.Close()
+	// Without a key the signature is not checked.
+	if db, err := OpenBytes(signed); err != nil {
+		t.Errorf("signed file without key: %v", err)
+	} else {
+		db.Close()
+	}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		pub    ed25519.PublicKey
+		reason string
+	}{
+		{"wrong key", signed, otherPub, "signature mismatch"},
+		{"unsigned", unsigned, pub, "file is not signed"},
+	}
+	for _, tt := range tests {
+		_, err := OpenBytes(tt.data, WithPublicKey(tt.pub))
+		var se *SignatureError
+		if !errors.As(err, &se) || se.Reason != tt.reason {
+			t.Errorf("%s: %v, want SignatureError %q", tt.name, err, tt.reason)
+		}
+	}
+
+	// A tampered signed file fails the checksum first; without the checksum
+	// the signature catches it.
+	tampered := bytes.Clone(signed)
+	tampered[len(tampered)/2] ^= 0x01
+	if _, err := OpenBytes(tampered, WithPublicKey(pub)); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("tampered: %v, want ErrChecksumMismatch", err)
+	}
+	var se *SignatureError
+	if _, err := OpenBytes(tampered, WithPublicKey(pub), WithoutChecksum()); !errors.As(err, &se) {
+		t.Errorf("tampered without checksum: %v, want SignatureError", err)
+	}
+}